@@ -1,67 +1,111 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/20        Yusan Kurban
- */
-
-package general
-
-import (
-	"github.com/labstack/echo"
-	"github.com/labstack/gommon/log"
-	"net/http"
-)
-
-var (
-	code = http.StatusInternalServerError
-	msg  string
-)
-
-func EchoRestfulErrorHandler(err error, c echo.Context) {
-	log.Error(err)
-
-	if resp, ok := err.(*ErrorResp); ok {
-		code = resp.Code
-		msg = resp.Message
-	} else {
-		msg = http.StatusText(code)
-	}
-
-	if !c.Response().Committed {
-		if c.Request().Method == echo.HEAD {
-			err := c.NoContent(code)
-			if err != nil {
-				c.Logger().Error(err)
-			}
-		} else {
-			err := c.JSON(code, NewErrorWithMessage(code, msg))
-
-			if err != nil {
-				c.Logger().Error(err)
-			}
-		}
-	}
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/20        Yusan Kurban
+ */
+
+package general
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+
+	"ShopApi/general/errcode"
+	"ShopApi/log"
+)
+
+// EchoRestfulErrorHandler answers errors with the HTTP status of their
+// errcode and a localized message. Server-side failures never expose the
+// message set by the handler, which may carry database error text.
+func EchoRestfulErrorHandler(err error, c echo.Context) {
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	logger := log.Logger.With(zap.String("requestid", requestID))
+
+	var (
+		entry  errcode.Entry
+		msg    string
+		fields []FieldError
+	)
+
+	switch e := err.(type) {
+	case *ErrorResp:
+		entry = errcode.Lookup(e.Code)
+		if entry.Status < http.StatusInternalServerError {
+			msg = e.Message
+			fields = e.Fields
+		}
+	case *echo.HTTPError:
+		entry = errcode.FromStatus(e.Code)
+	default:
+		entry = errcode.Lookup(errcode.ErrInternal)
+	}
+
+	if entry.Status >= http.StatusInternalServerError {
+		logger.Error("Request failed:", err)
+	} else {
+		logger.Debug("Request rejected: %v", err)
+	}
+
+	if msg == "" {
+		msg = entry.Message(c.Request().Header.Get("Accept-Language"))
+	}
+
+	if c.Response().Committed {
+		return
+	}
+
+	if c.Request().Method == echo.HEAD {
+		err = c.NoContent(entry.Status)
+	} else {
+		err = c.JSON(entry.Status, errorBody(entry, msg, requestID, fields))
+	}
+
+	if err != nil {
+		logger.Error("Write error response with error:", err)
+	}
+}
+
+func errorBody(entry errcode.Entry, msg, requestID string, fields []FieldError) interface{} {
+	if FeatureEnabled(FeatureLegacyResponse) {
+		return &ErrorResp{
+			Code:      entry.Code,
+			Key:       entry.Key,
+			Message:   msg,
+			RequestID: requestID,
+			Fields:    fields,
+		}
+	}
+
+	return &Envelope{
+		Code:      entry.Code,
+		Key:       entry.Key,
+		Message:   msg,
+		RequestID: requestID,
+		Fields:    fields,
+	}
+}
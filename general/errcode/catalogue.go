@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package errcode
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Entry describes an error code: a stable key for clients to switch on, the
+// HTTP status it is answered with and its messages.
+type Entry struct {
+	Code   int
+	Key    string
+	Status int
+	Zh     string
+	En     string
+}
+
+// Message returns the message in the language of an Accept-Language header,
+// defaulting to Chinese.
+func (e Entry) Message(acceptLanguage string) string {
+	if IsEnglish(acceptLanguage) {
+		return e.En
+	}
+
+	return e.Zh
+}
+
+// IsEnglish reports whether English is the preferred language of an
+// Accept-Language header.
+func IsEnglish(acceptLanguage string) bool {
+	lang := strings.TrimSpace(strings.SplitN(acceptLanguage, ",", 2)[0])
+
+	return strings.HasPrefix(strings.ToLower(lang), "en")
+}
+
+// ErrInformation shares its value with ErrMysqlfound and so its entry.
+var catalogue = map[int]Entry{
+	ErrSucceed:             {Key: "ok", Status: http.StatusOK, Zh: "成功", En: "OK"},
+	ErrInvalidParams:       {Key: "invalid_params", Status: http.StatusBadRequest, Zh: "参数错误", En: "Invalid parameters"},
+	ErrMysql:               {Key: "database_error", Status: http.StatusInternalServerError, Zh: "数据库错误", En: "Database error"},
+	ErrDelete:              {Key: "logout_failed", Status: http.StatusInternalServerError, Zh: "登出失败", En: "Logout failed"},
+	ErrMysqlfound:          {Key: "record_not_found", Status: http.StatusNotFound, Zh: "记录不存在", En: "Record not found"},
+	ErrNameFormat:          {Key: "invalid_name_format", Status: http.StatusBadRequest, Zh: "用户名格式错误", En: "Invalid name format"},
+	ErrGetsess:             {Key: "session_error", Status: http.StatusInternalServerError, Zh: "会话错误", En: "Session error"},
+	ErrInvalidOrdersStatus: {Key: "invalid_order_status", Status: http.StatusBadRequest, Zh: "订单状态错误", En: "Invalid order status"},
+	ErrOrdersNotFound:      {Key: "order_not_found", Status: http.StatusNotFound, Zh: "订单不存在", En: "Order not found"},
+	NoOrder:                {Key: "no_order", Status: http.StatusNotFound, Zh: "没有订单", En: "No order"},
+	ErrCategoriesNotFound:  {Key: "category_not_found", Status: http.StatusNotFound, Zh: "分类不存在", En: "Category not found"},
+	ErrNotFound:            {Key: "not_found", Status: http.StatusNotFound, Zh: "资源不存在", En: "Not found"},
+	ErrAccess:              {Key: "access_denied", Status: http.StatusForbidden, Zh: "无权访问", En: "Access denied"},
+	ErrInvalidPhone:        {Key: "invalid_phone", Status: http.StatusBadRequest, Zh: "手机号格式错误", En: "Invalid phone number"},
+	ErrInput:               {Key: "invalid_input", Status: http.StatusBadRequest, Zh: "输入错误", En: "Invalid input"},
+	ErrMethodNotAllowed:    {Key: "method_not_allowed", Status: http.StatusMethodNotAllowed, Zh: "请求方法不允许", En: "Method not allowed"},
+	ErrInvalidCursor:       {Key: "invalid_cursor", Status: http.StatusBadRequest, Zh: "分页游标无效", En: "Invalid cursor"},
+
+	ErrLoginRequired:      {Key: "login_required", Status: http.StatusUnauthorized, Zh: "需要登录", En: "Login required"},
+	ErrPermissionDenied:   {Key: "permission_denied", Status: http.StatusForbidden, Zh: "权限不足", En: "Permission denied"},
+	ErrAdminLoginRequired: {Key: "admin_login_required", Status: http.StatusUnauthorized, Zh: "需要管理员登录", En: "Admin login required"},
+	ErrAccountDisabled:    {Key: "account_disabled", Status: http.StatusForbidden, Zh: "账号已停用", En: "Account disabled"},
+	ErrBadCredentials:     {Key: "bad_credentials", Status: http.StatusUnauthorized, Zh: "用户名或密码错误", En: "Incorrect username or password"},
+	ErrWrongPassword:      {Key: "wrong_password", Status: http.StatusBadRequest, Zh: "原密码错误", En: "Incorrect current password"},
+	ErrSamePassword:       {Key: "same_password", Status: http.StatusBadRequest, Zh: "新密码不能与原密码相同", En: "The new password is the same as the current one"},
+
+	ErrTooManyRequests: {Key: "too_many_requests", Status: http.StatusTooManyRequests, Zh: "请求过于频繁", En: "Too many requests"},
+
+	ErrWechatUnavailable: {Key: "wechat_unavailable", Status: http.StatusServiceUnavailable, Zh: "暂不支持微信登录", En: "WeChat login is unavailable"},
+	ErrInvalidWechatCode: {Key: "invalid_wechat_code", Status: http.StatusUnauthorized, Zh: "微信授权码无效或已使用", En: "Invalid or used WeChat code"},
+	ErrWechatBound:       {Key: "wechat_bound", Status: http.StatusConflict, Zh: "该微信已绑定其他账号", En: "The WeChat account is bound to another account"},
+	ErrWechatUnbind:      {Key: "wechat_unbind_refused", Status: http.StatusConflict, Zh: "微信注册的账号不能解绑微信", En: "An account created with WeChat can't unbind it"},
+
+	ErrSMSUnavailable:  {Key: "sms_unavailable", Status: http.StatusServiceUnavailable, Zh: "暂时无法发送短信", En: "SMS is unavailable"},
+	ErrInvalidSMSCode:  {Key: "invalid_sms_code", Status: http.StatusBadRequest, Zh: "验证码错误或已失效", En: "Invalid or expired verification code"},
+	ErrSMSTooFrequent:  {Key: "sms_too_frequent", Status: http.StatusTooManyRequests, Zh: "验证码发送过于频繁，请稍后再试", En: "Verification codes requested too often, try again later"},
+	ErrPhoneRegistered: {Key: "phone_registered", Status: http.StatusConflict, Zh: "该手机号已被其他账号使用", En: "The phone number is used by another account"},
+
+	ErrInvalidResetToken: {Key: "invalid_reset_token", Status: http.StatusBadRequest, Zh: "重置密码凭证无效或已过期", En: "Invalid or expired password reset token"},
+
+	ErrRoleExists:     {Key: "role_exists", Status: http.StatusConflict, Zh: "角色已存在", En: "The role already exists"},
+	ErrBuiltinRole:    {Key: "builtin_role", Status: http.StatusConflict, Zh: "超级管理员角色不能修改", En: "The super admin role can't be changed"},
+	ErrLastSuperAdmin: {Key: "last_super_admin", Status: http.StatusConflict, Zh: "至少需要保留一个超级管理员", En: "The last super admin can't lose the role"},
+
+	ErrInvalidSuspension: {Key: "invalid_suspension", Status: http.StatusBadRequest, Zh: "停用截止时间必须晚于当前时间", En: "The suspension must end in the future"},
+
+	ErrNoConnection:      {Key: "no_connection", Status: http.StatusServiceUnavailable, Zh: "服务暂不可用", En: "Service unavailable"},
+	ErrDBOperationFailed: {Key: "db_operation_failed", Status: http.StatusInternalServerError, Zh: "数据库操作失败", En: "Database operation failed"},
+	ErrInternal:          {Key: "internal_error", Status: http.StatusInternalServerError, Zh: "服务器内部错误", En: "Internal server error"},
+}
+
+func init() {
+	for code, e := range catalogue {
+		e.Code = code
+		catalogue[code] = e
+	}
+}
+
+// Lookup returns the entry of code, or the ErrInternal entry for codes that
+// aren't in the catalogue.
+func Lookup(code int) Entry {
+	if e, ok := catalogue[code]; ok {
+		return e
+	}
+
+	return catalogue[ErrInternal]
+}
+
+// FromStatus returns the entry answering an HTTP status raised outside the
+// handlers, such as an unknown route.
+func FromStatus(status int) Entry {
+	switch status {
+	case http.StatusNotFound:
+		return catalogue[ErrNotFound]
+	case http.StatusMethodNotAllowed:
+		return catalogue[ErrMethodNotAllowed]
+	case http.StatusUnauthorized:
+		return catalogue[ErrLoginRequired]
+	case http.StatusForbidden:
+		return catalogue[ErrAccess]
+	case http.StatusTooManyRequests:
+		return catalogue[ErrTooManyRequests]
+	case http.StatusServiceUnavailable:
+		return catalogue[ErrNoConnection]
+	}
+
+	if status >= 400 && status < 500 {
+		e := catalogue[ErrInvalidParams]
+		e.Status = status
+
+		return e
+	}
+
+	return catalogue[ErrInternal]
+}
@@ -1,62 +1,48 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/19        Sun Anxiang
- */
-
-package utility
-
-import (
-	"fmt"
-	"math/rand"
-	"time"
-
-	"ShopApi/server/initcache"
-)
-
-func CreateCode(phone string) error {
-	code := GenerateCode()
-
-	err := initcache.Bm.Put(phone, code, 60*time.Second)
-	if err == nil {
-		fmt.Println("code", code)
-	}
-
-	return err
-}
-
-func VerifyCode(phone, code string) bool {
-	codeSaved := initcache.Bm.Get(phone)
-
-	return code == codeSaved
-}
-
-func GenerateCode() string {
-	num := rand.New(rand.NewSource(time.Now().UnixNano()))
-	code := fmt.Sprintf("%06v", num.Int31n(999999))
-
-	return code
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package general
+
+import (
+	"sync/atomic"
+)
+
+var features atomic.Value
+
+// SetFeatures replaces the set of feature toggles.
+func SetFeatures(f map[string]bool) {
+	toggles := make(map[string]bool, len(f))
+	for name, on := range f {
+		toggles[name] = on
+	}
+
+	features.Store(toggles)
+}
+
+// FeatureEnabled reports whether the named toggle is switched on.
+func FeatureEnabled(name string) bool {
+	toggles, _ := features.Load().(map[string]bool)
+
+	return toggles[name]
+}
@@ -0,0 +1,80 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package general
+
+// Admin permissions. A route of the admin API names the permission it
+// requires; roles grant sets of them.
+const (
+	PermProductWrite  = "product:write"
+	PermCategoryWrite = "category:write"
+	PermOrderWrite    = "order:write"
+	PermOrderRefund   = "order:refund"
+	PermRoleManage    = "role:manage"
+	PermUserManage    = "user:manage"
+
+	// PermAll is granted to the super admin role only and includes every
+	// other permission.
+	PermAll = "*"
+)
+
+// Built-in roles, created by zdoc/mysql/shop.sql.
+const (
+	RoleSuper           = "super"
+	RoleCatalogManager  = "catalog"
+	RoleOrderOperator   = "order"
+	RoleCustomerService = "service"
+)
+
+// Permissions lists the permissions a role may be granted.
+var Permissions = []string{
+	PermProductWrite,
+	PermCategoryWrite,
+	PermOrderWrite,
+	PermOrderRefund,
+	PermRoleManage,
+	PermUserManage,
+}
+
+// IsPermission reports whether name is one of Permissions.
+func IsPermission(name string) bool {
+	for _, p := range Permissions {
+		if p == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Grants reports whether the permissions granted include perm.
+func Grants(granted []string, perm string) bool {
+	for _, p := range granted {
+		if p == perm || p == PermAll {
+			return true
+		}
+	}
+
+	return false
+}
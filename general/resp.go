@@ -1,54 +1,131 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/18        Yusan Kurban
- */
-
-package general
-
-import (
-	"ShopApi/general/errcode"
-)
-
-type ErrorResp struct {
-	Code    int    `json:"status"`
-	Message string `json:"message"`
-}
-
-func NewErrorWithMessage(code int, msg string) *ErrorResp {
-	if code == errcode.ErrSucceed {
-		msg = ""
-	}
-
-	return &ErrorResp{
-		Code:    code,
-		Message: msg,
-	}
-}
-
-func (this *ErrorResp) Error() string {
-	return this.Message
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/18        Yusan Kurban
+ */
+
+package general
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/general/errcode"
+)
+
+// FeatureLegacyResponse answers with the bare payload and the old error body
+// instead of the envelope, for clients that have not migrated yet.
+const FeatureLegacyResponse = "legacyresponse"
+
+// Envelope is the body of every response: Data carries the payload of a
+// successful request, Meta the pagination of a list.
+type Envelope struct {
+	Code      int          `json:"code"`
+	Key       string       `json:"key,omitempty"`
+	Message   string       `json:"message"`
+	Data      interface{}  `json:"data"`
+	Meta      *Meta        `json:"meta,omitempty"`
+	RequestID string       `json:"requestid,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+// Meta describes one page of a list. Page is left out when the page was
+// selected with a cursor.
+type Meta struct {
+	Page       uint64 `json:"page,omitempty"`
+	PageSize   uint64 `json:"page_size"`
+	Total      uint64 `json:"total"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// NewMeta describes a page of a list of total items followed by the page at
+// next, if any.
+func NewMeta(page, pageSize, total uint64, next string) *Meta {
+	return &Meta{
+		Page:       page,
+		PageSize:   pageSize,
+		Total:      total,
+		HasMore:    next != "",
+		NextCursor: next,
+	}
+}
+
+// Respond answers a successful request with data.
+func Respond(c echo.Context, data interface{}) error {
+	return RespondList(c, data, nil)
+}
+
+// RespondList answers a successful list request with one page of data.
+func RespondList(c echo.Context, data interface{}, meta *Meta) error {
+	if FeatureEnabled(FeatureLegacyResponse) {
+		return c.JSON(http.StatusOK, data)
+	}
+
+	entry := errcode.Lookup(errcode.ErrSucceed)
+
+	return c.JSON(http.StatusOK, &Envelope{
+		Code:    entry.Code,
+		Message: entry.Message(c.Request().Header.Get("Accept-Language")),
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
+// ErrorResp is an error returned by a handler, and the error body of the
+// legacy response. Code is an errcode value, not an HTTP status.
+type ErrorResp struct {
+	Code      int          `json:"status"`
+	Key       string       `json:"key,omitempty"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"requestid,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+}
+
+// NewError returns an error answered with the catalogue message of code.
+func NewError(code int) *ErrorResp {
+	return &ErrorResp{Code: code}
+}
+
+func NewErrorWithMessage(code int, msg string) *ErrorResp {
+	if code == errcode.ErrSucceed {
+		msg = ""
+	}
+
+	return &ErrorResp{
+		Code:    code,
+		Message: msg,
+	}
+}
+
+func (this *ErrorResp) Error() string {
+	if this.Message == "" {
+		return errcode.Lookup(this.Code).Key
+	}
+
+	return this.Message
+}
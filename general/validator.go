@@ -1,49 +1,187 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/05/22        Feng Yifei
- */
-
-package general
-
-import (
-	"github.com/labstack/echo"
-	"gopkg.in/go-playground/validator.v9"
-)
-
-type EchoValidator struct {
-	validator *validator.Validate
-}
-
-func (this *EchoValidator) Validate(i interface{}) error {
-	return this.validator.Struct(i)
-}
-
-func NewEchoValidator() echo.Validator {
-	return &EchoValidator{
-		validator: validator.New(),
-	}
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/05/22        Feng Yifei
+ */
+
+package general
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/zh"
+	"github.com/go-playground/universal-translator"
+	"github.com/labstack/echo"
+	"gopkg.in/go-playground/validator.v9"
+	en_translations "gopkg.in/go-playground/validator.v9/translations/en"
+
+	"ShopApi/general/errcode"
+)
+
+// FieldError is a field that failed validation and why.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+var (
+	validate   = validator.New()
+	translator = ut.New(zh.New(), zh.New(), en.New())
+
+	zhTrans, _ = translator.GetTranslator("zh")
+	enTrans, _ = translator.GetTranslator("en")
+)
+
+// zhMessages are the Chinese messages of the tags used in this project. Tags
+// measuring a size have one message for strings and one for numbers.
+var zhMessages = map[string][2]string{
+	"required": {"{0}为必填字段", "{0}为必填字段"},
+	"len":      {"{0}长度必须是{1}个字符", "{0}必须等于{1}"},
+	"min":      {"{0}长度不能少于{1}个字符", "{0}不能小于{1}"},
+	"max":      {"{0}长度不能超过{1}个字符", "{0}不能大于{1}"},
+	"gt":       {"{0}长度必须多于{1}个字符", "{0}必须大于{1}"},
+	"gte":      {"{0}长度不能少于{1}个字符", "{0}必须大于或等于{1}"},
+	"lt":       {"{0}长度必须少于{1}个字符", "{0}必须小于{1}"},
+	"lte":      {"{0}长度不能超过{1}个字符", "{0}必须小于或等于{1}"},
+	"email":    {"{0}必须是有效的邮箱地址", "{0}必须是有效的邮箱地址"},
+	"url":      {"{0}必须是有效的 URL", "{0}必须是有效的 URL"},
+	"numeric":  {"{0}必须是数字", "{0}必须是数字"},
+	"number":   {"{0}只能包含数字", "{0}只能包含数字"},
+	"alphanum": {"{0}只能包含字母和数字", "{0}只能包含字母和数字"},
+}
+
+func init() {
+	// report fields by their JSON name
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+
+		if name == "" {
+			return field.Name
+		}
+
+		return name
+	})
+
+	if err := en_translations.RegisterDefaultTranslations(validate, enTrans); err != nil {
+		panic(err)
+	}
+
+	for tag, messages := range zhMessages {
+		registerTranslation(tag, zhTrans, messages[0], messages[1])
+	}
+}
+
+// RegisterValidation adds a custom tag with its Chinese and English messages.
+// It must be called during package initialization.
+func RegisterValidation(tag string, fn validator.Func, zhMessage, enMessage string) {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		panic(err)
+	}
+
+	registerTranslation(tag, zhTrans, zhMessage, zhMessage)
+	registerTranslation(tag, enTrans, enMessage, enMessage)
+}
+
+func registerTranslation(tag string, trans ut.Translator, text, number string) {
+	register := func(trans ut.Translator) error {
+		if err := trans.Add(tag+"-string", text, true); err != nil {
+			return err
+		}
+
+		return trans.Add(tag+"-number", number, true)
+	}
+
+	translate := func(trans ut.Translator, fe validator.FieldError) string {
+		key := tag + "-number"
+		if fe.Kind() == reflect.String {
+			key = tag + "-string"
+		}
+
+		msg, err := trans.T(key, fe.Field(), fe.Param())
+		if err != nil {
+			return fe.(error).Error()
+		}
+
+		return msg
+	}
+
+	if err := validate.RegisterTranslation(tag, trans, register, translate); err != nil {
+		panic(err)
+	}
+}
+
+type EchoValidator struct {
+	validator *validator.Validate
+}
+
+func (this *EchoValidator) Validate(i interface{}) error {
+	return this.validator.Struct(i)
+}
+
+func NewEchoValidator() echo.Validator {
+	return &EchoValidator{
+		validator: validate,
+	}
+}
+
+// BindAndValidate binds the request into v and checks its validate tags. A
+// failure is an ErrInvalidParams error listing each invalid field in the
+// language of the request.
+func BindAndValidate(c echo.Context, v interface{}) error {
+	if err := c.Bind(v); err != nil {
+		return NewErrorWithMessage(errcode.ErrInvalidParams, err.Error())
+	}
+
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	errs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	trans := zhTrans
+	if errcode.IsEnglish(c.Request().Header.Get("Accept-Language")) {
+		trans = enTrans
+	}
+
+	resp := NewError(errcode.ErrInvalidParams)
+	for _, fe := range errs {
+		resp.Fields = append(resp.Fields, FieldError{
+			Field:   fe.Field(),
+			Message: fe.Translate(trans),
+		})
+	}
+
+	return resp
+}
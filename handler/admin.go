@@ -0,0 +1,231 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/models"
+	"ShopApi/utility"
+)
+
+const adminIdentityKey = "adminidentity"
+
+// AdminIdentity is the admin calling a route of the admin API.
+type AdminIdentity struct {
+	AdminID   uint64
+	SessionID string
+
+	permissions []string // loaded by adminCan
+}
+
+// MustAdmin accepts an "Authorization: Bearer" admin access token whose
+// session is still active and whose admin is not disabled, and stores the
+// caller's AdminIdentity in the context. User tokens and sessions are
+// refused.
+func MustAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := c.Request().Header.Get(echo.HeaderAuthorization)
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return general.NewError(errcode.ErrAdminLoginRequired)
+		}
+
+		claims, err := utility.ParseAdminToken(strings.TrimPrefix(auth, "Bearer "))
+		if err != nil {
+			requestLog(c).Debug("Reject admin token: %v", err)
+
+			return general.NewError(errcode.ErrAdminLoginRequired)
+		}
+
+		s, err := models.AdminService.ActiveSession(claims.SessionID)
+		if err != nil || s.AdminID != claims.UserID {
+			if err == nil || err == gorm.ErrRecordNotFound {
+				return general.NewError(errcode.ErrAdminLoginRequired)
+			}
+			requestLog(c).Error("Mysql error:", err)
+
+			return general.NewError(errcode.ErrMysql)
+		}
+
+		admin, err := models.AdminService.GetByID(s.AdminID)
+		if err != nil {
+			requestLog(c).Error("Admin of session not found:", err)
+
+			return general.NewError(errcode.ErrAdminLoginRequired)
+		}
+
+		if admin.Status != general.AdminActive {
+			return general.NewError(errcode.ErrAccountDisabled)
+		}
+
+		c.Set(adminIdentityKey, &AdminIdentity{AdminID: admin.ID, SessionID: s.ID})
+
+		return next(c)
+	}
+}
+
+// CurrentAdmin returns the caller set by MustAdmin, or nil outside the admin
+// API.
+func CurrentAdmin(c echo.Context) *AdminIdentity {
+	identity, _ := c.Get(adminIdentityKey).(*AdminIdentity)
+
+	return identity
+}
+
+func AdminLogin(c echo.Context) error {
+	var (
+		err error
+		req models.AdminLogin
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	admin, match, err := models.AdminService.Login(req.Username, req.Pass)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	if !match {
+		requestLog(c).Info("Admin login of %s refused", req.Username)
+		loginsFailed.With("admin").Inc()
+
+		return general.NewError(errcode.ErrBadCredentials)
+	}
+
+	if admin.Status != general.AdminActive {
+		return general.NewError(errcode.ErrAccountDisabled)
+	}
+
+	device := req.Device
+	if device == "" {
+		device = c.Request().UserAgent()
+	}
+	if len(device) > maxDeviceLength {
+		device = device[:maxDeviceLength]
+	}
+
+	s := &models.AdminSession{
+		ID:      utility.NewSessionID(),
+		AdminID: admin.ID,
+		Device:  device,
+		IP:      clientIP(c),
+		Expires: time.Now().Add(utility.AdminTokenTTL()),
+	}
+
+	if err = models.AdminService.CreateSession(s); err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	token, claims := utility.NewAdminToken(admin.ID, s.ID)
+
+	return general.Respond(c, &TokenResp{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   claims.ExpiresAt - claims.IssuedAt,
+	})
+}
+
+func AdminLogout(c echo.Context) error {
+	identity := CurrentAdmin(c)
+
+	if err := models.AdminService.RevokeSession(identity.AdminID, identity.SessionID); err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+	utility.RevokeToken(identity.SessionID)
+
+	return general.Respond(c, nil)
+}
+
+func AdminProfile(c echo.Context) error {
+	admin, err := models.AdminService.GetByID(CurrentAdmin(c).AdminID)
+	if err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	return general.Respond(c, admin)
+}
+
+// AdminChangePassword replaces the password of the caller and ends its other
+// sessions.
+func AdminChangePassword(c echo.Context) error {
+	var (
+		err error
+		req models.AdminPassword
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if req.Pass == req.NewPass {
+		return general.NewError(errcode.ErrSamePassword)
+	}
+
+	identity := CurrentAdmin(c)
+
+	match, err := models.AdminService.ChangePassword(identity.AdminID, req.Pass, req.NewPass)
+	if err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	if !match {
+		return general.NewError(errcode.ErrWrongPassword)
+	}
+
+	others, err := models.AdminService.RevokeSessions(identity.AdminID, identity.SessionID)
+	if err != nil {
+		requestLog(c).Error("Revoke other sessions with error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	for _, sid := range others {
+		utility.RevokeToken(sid)
+	}
+
+	return general.Respond(c, nil)
+}
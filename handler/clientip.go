@@ -0,0 +1,127 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/labstack/echo"
+)
+
+// proxies holds the networks of the reverse proxies whose X-Forwarded-For
+// and X-Real-IP headers are believed.
+var proxies atomic.Value // []*net.IPNet
+
+// IsValidProxy reports whether s is an IP address or a CIDR network.
+func IsValidProxy(s string) bool {
+	_, err := parseProxy(s)
+
+	return err == nil
+}
+
+func parseProxy(s string) (*net.IPNet, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		bits := 8 * len(ip.To4())
+		if bits == 0 {
+			bits = 8 * net.IPv6len
+		}
+
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, n, err := net.ParseCIDR(s)
+
+	return n, err
+}
+
+// SetTrustedProxies sets the proxies allowed to report the address of
+// clients. Invalid entries are skipped; none means that only the address
+// of the connection is used.
+func SetTrustedProxies(cidrs []string) {
+	var nets []*net.IPNet
+
+	for _, s := range cidrs {
+		if n, err := parseProxy(s); err == nil {
+			nets = append(nets, n)
+		}
+	}
+
+	proxies.Store(nets)
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	nets, _ := proxies.Load().([]*net.IPNet)
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the address of the client. Unlike echo's RealIP, which
+// believes headers any client can set, it only reads X-Forwarded-For and
+// X-Real-IP when the connection comes from a trusted proxy, and then skips
+// the trusted hops from the right of X-Forwarded-For.
+func clientIP(c echo.Context) string {
+	req := c.Request()
+
+	addr, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		addr = req.RemoteAddr
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil || !isTrustedProxy(ip) {
+		return addr
+	}
+
+	if xff := req.Header.Get(echo.HeaderXForwardedFor); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			hopIP := net.ParseIP(hop)
+			if hopIP == nil {
+				break
+			}
+
+			addr = hop
+			if !isTrustedProxy(hopIP) {
+				break
+			}
+		}
+
+		return addr
+	}
+
+	if real := strings.TrimSpace(req.Header.Get(echo.HeaderXRealIP)); net.ParseIP(real) != nil {
+		return real
+	}
+
+	return addr
+}
@@ -0,0 +1,66 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+)
+
+func TestClientIP(t *testing.T) {
+	SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	defer SetTrustedProxies(nil)
+
+	cases := []struct {
+		remote, xff, realIP, want string
+	}{
+		{"203.0.113.9:5000", "", "", "203.0.113.9"},
+		{"203.0.113.9:5000", "1.2.3.4", "5.6.7.8", "203.0.113.9"},
+		{"10.1.2.3:5000", "1.2.3.4", "", "1.2.3.4"},
+		{"10.1.2.3:5000", "6.6.6.6, 1.2.3.4, 10.9.9.9", "", "1.2.3.4"},
+		{"192.168.1.1:5000", "10.0.0.1, 10.0.0.2", "", "10.0.0.1"},
+		{"10.1.2.3:5000", "garbage, 1.2.3.4", "", "1.2.3.4"},
+		{"10.1.2.3:5000", "", "5.6.7.8", "5.6.7.8"},
+		{"192.168.1.2:5000", "1.2.3.4", "", "192.168.1.2"},
+	}
+
+	e := echo.New()
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.xff != "" {
+			req.Header.Set(echo.HeaderXForwardedFor, tc.xff)
+		}
+		if tc.realIP != "" {
+			req.Header.Set(echo.HeaderXRealIP, tc.realIP)
+		}
+
+		if got := clientIP(e.NewContext(req, httptest.NewRecorder())); got != tc.want {
+			t.Errorf("clientIP(%s, X-Forwarded-For %q, X-Real-IP %q) = %s, want %s", tc.remote, tc.xff, tc.realIP, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// CORSConfig describes which cross-origin callers may use the API.
+type CORSConfig struct {
+	// Hosts lists allowed origins such as "https://m.example.com". A leading
+	// "*." matches any subdomain, an entry without scheme matches both http
+	// and https, and "*" allows every origin, though never with
+	// credentials.
+	Hosts       []string
+	Methods     []string
+	Headers     []string
+	Credentials bool
+	MaxAge      time.Duration
+}
+
+type originPattern struct {
+	scheme string // empty matches http and https
+	host   string // with port, if any
+	suffix bool   // host is a ".example.com" subdomain suffix
+}
+
+type corsPolicy struct {
+	any         bool
+	origins     []originPattern
+	allowed     []string
+	methods     string
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+var cors atomic.Value
+
+// exposedHeaders are the response headers pages may read besides the
+// CORS-safelisted ones.
+var exposedHeaders = strings.Join([]string{
+	echo.HeaderXRequestID, HeaderAPIVersion, HeaderDeprecation, HeaderSunset, HeaderLink,
+}, ", ")
+
+// SetCORS replaces the CORS policy. Invalid hosts are skipped.
+func SetCORS(conf CORSConfig) {
+	policy := &corsPolicy{
+		allowed:     conf.Methods,
+		methods:     strings.Join(conf.Methods, ","),
+		headers:     strings.Join(conf.Headers, ","),
+		credentials: conf.Credentials,
+		maxAge:      strconv.Itoa(int(conf.MaxAge / time.Second)),
+	}
+
+	for _, host := range conf.Hosts {
+		if host == "*" {
+			policy.any = true
+			continue
+		}
+
+		if pattern, ok := parseOriginPattern(host); ok {
+			policy.origins = append(policy.origins, pattern)
+		}
+	}
+
+	// any origin could otherwise read responses to the cookies of the user
+	if policy.any {
+		policy.credentials = false
+	}
+
+	cors.Store(policy)
+}
+
+// IsValidCORSHost reports whether host can be used in CORSConfig.Hosts.
+func IsValidCORSHost(host string) bool {
+	if host == "*" {
+		return true
+	}
+
+	_, ok := parseOriginPattern(host)
+
+	return ok
+}
+
+func parseOriginPattern(host string) (originPattern, bool) {
+	var pattern originPattern
+
+	host = strings.ToLower(strings.TrimSuffix(host, "/"))
+	if i := strings.Index(host, "://"); i >= 0 {
+		pattern.scheme, host = host[:i], host[i+3:]
+		if pattern.scheme != "http" && pattern.scheme != "https" {
+			return pattern, false
+		}
+	}
+
+	if strings.HasPrefix(host, "*.") {
+		pattern.suffix = true
+		host = host[1:]
+	}
+
+	if host == "" || host == "." || strings.ContainsAny(host, "/*?#@") {
+		return pattern, false
+	}
+	pattern.host = host
+
+	return pattern, true
+}
+
+func (p *corsPolicy) allow(origin string) bool {
+	if p.any {
+		return true
+	}
+
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	for _, o := range p.origins {
+		if o.scheme != "" && o.scheme != u.Scheme {
+			continue
+		}
+
+		if o.suffix {
+			if len(u.Host) > len(o.host) && strings.HasSuffix(u.Host, o.host) {
+				return true
+			}
+		} else if u.Host == o.host {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *corsPolicy) allowMethod(method string) bool {
+	for _, m := range p.allowed {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CORS answers preflight requests and adds the CORS response headers for
+// allowed origins. Requests from other origins get no CORS headers, so the
+// browser refuses to hand the response to the page.
+func CORS(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		policy, _ := cors.Load().(*corsPolicy)
+		req := c.Request()
+		header := c.Response().Header()
+		origin := req.Header.Get(echo.HeaderOrigin)
+
+		if policy == nil || origin == "" {
+			return next(c)
+		}
+
+		header.Add(echo.HeaderVary, echo.HeaderOrigin)
+
+		preflight := req.Method == echo.OPTIONS && req.Header.Get(echo.HeaderAccessControlRequestMethod) != ""
+		if !policy.allow(origin) {
+			if preflight {
+				return c.NoContent(http.StatusForbidden)
+			}
+
+			return next(c)
+		}
+
+		header.Set(echo.HeaderAccessControlAllowOrigin, origin)
+		if policy.credentials {
+			header.Set(echo.HeaderAccessControlAllowCredentials, "true")
+		}
+
+		if !preflight {
+			header.Set(echo.HeaderAccessControlExposeHeaders, exposedHeaders)
+
+			return next(c)
+		}
+
+		if !policy.allowMethod(req.Header.Get(echo.HeaderAccessControlRequestMethod)) {
+			return c.NoContent(http.StatusForbidden)
+		}
+
+		header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
+		header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
+		header.Set(echo.HeaderAccessControlAllowMethods, policy.methods)
+		if policy.headers != "" {
+			header.Set(echo.HeaderAccessControlAllowHeaders, policy.headers)
+		}
+		if policy.maxAge != "0" {
+			header.Set(echo.HeaderAccessControlMaxAge, policy.maxAge)
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+}
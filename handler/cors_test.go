@@ -0,0 +1,196 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+func newCORSServer() *echo.Echo {
+	SetCORS(CORSConfig{
+		Hosts:       []string{"https://m.example.com", "https://*.shop.com", "localhost:8080"},
+		Methods:     []string{"GET", "POST"},
+		Headers:     []string{"Content-Type", "Authorization"},
+		Credentials: true,
+		MaxAge:      10 * time.Minute,
+	})
+
+	e := echo.New()
+	e.Use(CORS)
+	e.POST("/api/v1/user/login", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	return e
+}
+
+func TestCORSSimpleRequest(t *testing.T) {
+	e := newCORSServer()
+
+	cases := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://m.example.com", true},
+		{"https://h5.shop.com", true},
+		{"https://a.b.shop.com", true},
+		{"http://localhost:8080", true},
+		{"https://localhost:8080", true},
+		{"http://m.example.com", false},
+		{"https://shop.com", false},
+		{"https://evilshop.com", false},
+		{"https://m.example.com.evil.com", false},
+		{"http://localhost:8081", false},
+		{"null", false},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(echo.POST, "/api/v1/user/login", nil)
+		req.Header.Set(echo.HeaderOrigin, tc.origin)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", tc.origin, rec.Code, http.StatusOK)
+		}
+
+		got := rec.Header().Get(echo.HeaderAccessControlAllowOrigin)
+		if tc.allowed {
+			if got != tc.origin {
+				t.Errorf("%s: Access-Control-Allow-Origin = %q, want the origin", tc.origin, got)
+			}
+			if rec.Header().Get(echo.HeaderAccessControlAllowCredentials) != "true" {
+				t.Errorf("%s: credentials not allowed", tc.origin)
+			}
+		} else if got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", tc.origin, got)
+		}
+
+		if rec.Header().Get(echo.HeaderVary) != echo.HeaderOrigin {
+			t.Errorf("%s: Vary = %q, want Origin", tc.origin, rec.Header().Get(echo.HeaderVary))
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	e := newCORSServer()
+
+	cases := []struct {
+		origin string
+		method string
+		status int
+	}{
+		{"https://m.example.com", "POST", http.StatusNoContent},
+		{"https://h5.shop.com", "GET", http.StatusNoContent},
+		{"https://m.example.com", "DELETE", http.StatusForbidden},
+		{"https://evil.com", "POST", http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(echo.OPTIONS, "/api/v1/user/login", nil)
+		req.Header.Set(echo.HeaderOrigin, tc.origin)
+		req.Header.Set(echo.HeaderAccessControlRequestMethod, tc.method)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != tc.status {
+			t.Errorf("%s %s: status = %d, want %d", tc.origin, tc.method, rec.Code, tc.status)
+		}
+
+		if tc.status != http.StatusNoContent {
+			continue
+		}
+
+		want := map[string]string{
+			echo.HeaderAccessControlAllowOrigin:      tc.origin,
+			echo.HeaderAccessControlAllowMethods:     "GET,POST",
+			echo.HeaderAccessControlAllowHeaders:     "Content-Type,Authorization",
+			echo.HeaderAccessControlAllowCredentials: "true",
+			echo.HeaderAccessControlMaxAge:           "600",
+		}
+		for k, v := range want {
+			if got := rec.Header().Get(k); got != v {
+				t.Errorf("%s %s: %s = %q, want %q", tc.origin, tc.method, k, got, v)
+			}
+		}
+	}
+}
+
+func TestCORSWithoutOrigin(t *testing.T) {
+	e := newCORSServer()
+
+	req := httptest.NewRequest(echo.POST, "/api/v1/user/login", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Header().Get(echo.HeaderAccessControlAllowOrigin) != "" {
+		t.Errorf("same-origin request: status = %d, headers = %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCORSAnyOriginWithoutCredentials(t *testing.T) {
+	SetCORS(CORSConfig{Hosts: []string{"*"}, Methods: []string{"POST"}, Credentials: true})
+	defer SetCORS(CORSConfig{})
+
+	e := echo.New()
+	e.Use(CORS)
+	e.POST("/api/v1/user/login", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(echo.POST, "/api/v1/user/login", nil)
+	req.Header.Set(echo.HeaderOrigin, "https://evil.example.org")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Header().Get(echo.HeaderAccessControlAllowOrigin) == "" {
+		t.Error("\"*\" refused an origin")
+	}
+	if got := rec.Header().Get(echo.HeaderAccessControlAllowCredentials); got != "" {
+		t.Errorf("\"*\" allowed credentials: %q", got)
+	}
+}
+
+func TestIsValidCORSHost(t *testing.T) {
+	valid := []string{"*", "https://m.example.com", "*.example.com", "http://localhost:8080"}
+	invalid := []string{"", "ftp://example.com", "https://", "https://*", "https://example.com/path", "https://a.*.com"}
+
+	for _, host := range valid {
+		if !IsValidCORSHost(host) {
+			t.Errorf("IsValidCORSHost(%q) = false, want true", host)
+		}
+	}
+
+	for _, host := range invalid {
+		if IsValidCORSHost(host) {
+			t.Errorf("IsValidCORSHost(%q) = true, want false", host)
+		}
+	}
+}
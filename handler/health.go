@@ -0,0 +1,190 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/orm"
+	"ShopApi/server/initcache"
+	"ShopApi/utility"
+)
+
+const (
+	readyTimeout = 2 * time.Second
+	readyProbe   = "readyz:probe"
+)
+
+var (
+	draining int32
+
+	errNotConnected = errors.New("not connected")
+	errCacheMiss    = errors.New("probe key not found")
+)
+
+// DependencyStatus is the result of checking one dependency: "up", "down"
+// or "timeout". Why a dependency is down is only logged, as /readyz needs
+// no login.
+type DependencyStatus struct {
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+}
+
+// Readiness is the body of /readyz.
+type Readiness struct {
+	Status   string                      `json:"status"`
+	Draining bool                        `json:"draining"`
+	Checks   map[string]DependencyStatus `json:"checks"`
+}
+
+// SetDraining marks the server as shutting down, which fails readiness so
+// that load balancers stop sending traffic.
+func SetDraining(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+
+	atomic.StoreInt32(&draining, v)
+}
+
+func isDraining() bool {
+	return atomic.LoadInt32(&draining) == 1
+}
+
+// Healthz reports that the process is alive.
+func Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz checks MySQL, the session store and the cache, and answers 503
+// if any of them is down or the server is draining.
+func Readyz(c echo.Context) error {
+	checks := map[string]func(context.Context) error{
+		"mysql": func(ctx context.Context) error {
+			if orm.Conn == nil {
+				return errNotConnected
+			}
+
+			return orm.Conn.DB().PingContext(ctx)
+		},
+		"session": func(ctx context.Context) error {
+			return utility.GlobalSessions.Store().Ping(ctx)
+		},
+		"cache": func(context.Context) error {
+			if err := initcache.Bm.Put(readyProbe, true, readyTimeout); err != nil {
+				return err
+			}
+
+			if !initcache.Bm.IsExist(readyProbe) {
+				return errCacheMiss
+			}
+
+			return nil
+		},
+	}
+
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+	)
+
+	ready := Readiness{
+		Status:   "ok",
+		Draining: isDraining(),
+		Checks:   make(map[string]DependencyStatus),
+	}
+
+	for name, fn := range checks {
+		wg.Add(1)
+		go func(name string, fn func(context.Context) error) {
+			defer wg.Done()
+
+			status, err := checkDependency(c.Request().Context(), fn)
+			if err != nil {
+				requestLog(c).Error("Readiness check of "+name+" failed:", err)
+			}
+
+			lock.Lock()
+			ready.Checks[name] = status
+			lock.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	for _, status := range ready.Checks {
+		if status.Status != "up" {
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	if ready.Draining {
+		code = http.StatusServiceUnavailable
+	}
+
+	if code != http.StatusOK {
+		ready.Status = "unavailable"
+	}
+
+	return c.JSON(code, ready)
+}
+
+// checkDependency runs fn, giving up after readyTimeout even if fn ignores
+// the context, and returns the error of a dependency that isn't up.
+func checkDependency(parent context.Context, fn func(context.Context) error) (DependencyStatus, error) {
+	ctx, cancel := context.WithTimeout(parent, readyTimeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	status := DependencyStatus{Status: "up", Latency: time.Since(start).String()}
+	switch {
+	case err == context.DeadlineExceeded:
+		status.Status = "timeout"
+	case err != nil:
+		status.Status = "down"
+	}
+
+	return status, err
+}
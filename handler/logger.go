@@ -0,0 +1,190 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	mrand "math/rand"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"ShopApi/log"
+)
+
+const (
+	requestIDKey    = "requestid"
+	loggerKey       = "logger"
+	maxRequestIDLen = 64
+)
+
+// AccessLogConfig controls the access log. Sample maps a route such as
+// "/api/v1/categories/get" to the fraction of its successful requests that
+// are logged; failed requests are always logged. Routes are matched
+// regardless of case, as the configuration lowercases map keys.
+type AccessLogConfig struct {
+	Enabled bool
+	Sample  map[string]float64
+}
+
+var accessLog atomic.Value
+
+func init() {
+	accessLog.Store(AccessLogConfig{Enabled: true})
+}
+
+// SetAccessLog replaces the access log settings.
+func SetAccessLog(conf AccessLogConfig) {
+	sample := make(map[string]float64, len(conf.Sample))
+	for route, rate := range conf.Sample {
+		sample[strings.ToLower(route)] = rate
+	}
+	conf.Sample = sample
+
+	accessLog.Store(conf)
+}
+
+// IsValidSampleRate reports whether rate is a fraction between 0 and 1.
+func IsValidSampleRate(rate float64) bool {
+	return rate >= 0 && rate <= 1 && !math.IsNaN(rate)
+}
+
+// RequestID takes the X-Request-ID of the request, or generates one if it is
+// missing or malformed, stores it in the context and echoes it in the
+// response.
+func RequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header.Get(echo.HeaderXRequestID)
+		if !isValidRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+		return next(c)
+	}
+}
+
+// CurrentRequestID returns the ID set by RequestID.
+func CurrentRequestID(c echo.Context) string {
+	id, _ := c.Get(requestIDKey).(string)
+
+	return id
+}
+
+// RequestLogger gives every request a child logger carrying its request ID,
+// route, caller and latency so far, and writes one access log line once the
+// response is sent.
+func RequestLogger(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		userID := func() string {
+			if identity := CurrentIdentity(c); identity != nil {
+				return strconv.FormatUint(identity.UserID, 10)
+			}
+
+			return ""
+		}
+
+		l := log.Logger.With(
+			zap.String("requestid", CurrentRequestID(c)),
+			zap.String("route", c.Request().Method+" "+c.Path()),
+			log.Lazy("userid", userID),
+			log.Lazy("latency", func() string {
+				return time.Since(start).String()
+			}),
+		)
+		c.Set(loggerKey, l)
+
+		// handle the error here so that the status is known when logging
+		err := next(c)
+		if err != nil {
+			c.Error(err)
+		}
+
+		conf := accessLog.Load().(AccessLogConfig)
+		if !conf.Enabled {
+			return nil
+		}
+
+		res := c.Response()
+		if rate, ok := conf.Sample[strings.ToLower(c.Path())]; ok && err == nil && res.Status < 400 && mrand.Float64() >= rate {
+			return nil
+		}
+
+		log.Logger.Fields(zapcore.InfoLevel, "access",
+			zap.String("requestid", CurrentRequestID(c)),
+			zap.String("method", c.Request().Method),
+			zap.String("route", c.Path()),
+			zap.String("uri", c.Request().RequestURI),
+			zap.Int("status", res.Status),
+			zap.Int64("bytes", res.Size),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("userid", userID()),
+			zap.String("ip", clientIP(c)),
+		)
+
+		return nil
+	}
+}
+
+// requestLog returns the logger of the request, falling back to log.Logger
+// outside RequestLogger.
+func requestLog(c echo.Context) *log.RecordLog {
+	if l, ok := c.Get(loggerKey).(*log.RecordLog); ok {
+		return l
+	}
+
+	return log.Logger
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
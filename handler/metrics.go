@@ -0,0 +1,139 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/metrics"
+	"ShopApi/orm"
+	"ShopApi/utility"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec("shop_http_requests_total",
+		"HTTP requests by method, route and status.", "method", "route", "status")
+	httpDuration = metrics.NewHistogramVec("shop_http_request_duration_seconds",
+		"HTTP request latency by method and route.", metrics.DefBuckets, "method", "route")
+	apiErrors = metrics.NewCounterVec("shop_errors_total",
+		"Errors returned to clients by errcode.", "errcode")
+
+	ordersCreated = metrics.NewCounter("shop_orders_created_total", "Orders created.")
+	cartsAdded    = metrics.NewCounter("shop_carts_added_total", "Products put into carts.")
+	loginsFailed  = metrics.NewCounterVec("shop_logins_failed_total",
+		"Failed logins by reason.", "reason")
+
+	deprecatedRequests = metrics.NewCounterVec("shop_deprecated_requests_total",
+		"Requests to deprecated routes by route.", "route")
+	permissionsDenied = metrics.NewCounterVec("shop_permission_denied_total",
+		"Admin requests refused for lack of a permission, by permission.", "permission")
+	smsCodes = metrics.NewCounterVec("shop_sms_codes_total",
+		"Verification codes by purpose and result: sent, failed, throttled or rejected.", "purpose", "result")
+)
+
+func init() {
+	dbStat := func(stat func(s sql.DBStats) float64) func() (float64, bool) {
+		return func() (float64, bool) {
+			if orm.Conn == nil {
+				return 0, false
+			}
+
+			return stat(orm.Conn.DB().Stats()), true
+		}
+	}
+
+	metrics.NewGaugeFunc("shop_db_open_connections", "Open connections to MySQL.",
+		dbStat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	metrics.NewGaugeFunc("shop_db_in_use_connections", "MySQL connections in use.",
+		dbStat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	metrics.NewGaugeFunc("shop_db_idle_connections", "Idle MySQL connections.",
+		dbStat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	metrics.NewCounterFunc("shop_db_wait_total", "Waits for a free MySQL connection.",
+		dbStat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	metrics.NewCounterFunc("shop_db_wait_seconds_total", "Time spent waiting for a free MySQL connection.",
+		dbStat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+
+	metrics.NewGaugeFunc("shop_sessions", "Live cookie sessions in the session store.", func() (float64, bool) {
+		n, err := utility.GlobalSessions.Store().Count()
+		if err != nil {
+			return 0, false
+		}
+
+		return float64(n), true
+	})
+}
+
+// Instrument counts requests, their latency and the errcode of failures.
+func Instrument(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		// handle the error here so that the status is known when counting
+		err := next(c)
+		if err != nil {
+			apiErrors.With(errorCode(err)).Inc()
+			c.Error(err)
+		}
+
+		method := c.Request().Method
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		httpRequests.With(method, route, strconv.Itoa(c.Response().Status)).Inc()
+		httpDuration.With(method, route).Observe(time.Since(start).Seconds())
+
+		return nil
+	}
+}
+
+// Metrics serves the metrics in the Prometheus text format.
+func Metrics(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	c.Response().WriteHeader(http.StatusOK)
+
+	_, err := metrics.Default.WriteTo(c.Response())
+
+	return err
+}
+
+func errorCode(err error) string {
+	switch e := err.(type) {
+	case *general.ErrorResp:
+		return fmt.Sprintf("%#x", e.Code)
+	case *echo.HTTPError:
+		return "http_" + strconv.Itoa(e.Code)
+	}
+
+	return "unknown"
+}
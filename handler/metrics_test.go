@@ -0,0 +1,135 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+)
+
+func scrape(t *testing.T, url string) map[string]string {
+	resp, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("scrape status = %d", resp.StatusCode)
+	}
+
+	if ct := resp.Header.Get(echo.HeaderContentType); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	samples := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			samples[line] = ""
+			continue
+		}
+
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			t.Fatalf("malformed sample %q", line)
+		}
+		samples[line[:i]] = line[i+1:]
+	}
+
+	return samples
+}
+
+func TestMetricsScrape(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = general.EchoRestfulErrorHandler
+	e.Use(Instrument)
+	e.GET("/metrics", Metrics)
+	e.GET("/ok", func(c echo.Context) error {
+		ordersCreated.Inc()
+
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/fail", func(c echo.Context) error {
+		return general.NewError(errcode.ErrInvalidParams)
+	})
+
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	for _, path := range []string{"/ok", "/ok", "/fail"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	samples := scrape(t, server.URL)
+
+	cases := map[string]string{
+		`shop_http_requests_total{method="GET",route="/ok",status="200"}`:               "2",
+		`shop_http_requests_total{method="GET",route="/fail",status="400"}`:             "1",
+		`shop_http_request_duration_seconds_bucket{method="GET",route="/ok",le="+Inf"}`: "2",
+		`shop_http_request_duration_seconds_count{method="GET",route="/ok"}`:            "2",
+		`shop_errors_total{errcode="0x1"}`:                                              "1",
+		`shop_orders_created_total`:                                                     "2",
+	}
+	for series, want := range cases {
+		if got, ok := samples[series]; !ok || got != want {
+			t.Errorf("%s = %q, want %q", series, got, want)
+		}
+	}
+
+	for _, line := range []string{
+		"# TYPE shop_http_requests_total counter",
+		"# TYPE shop_http_request_duration_seconds histogram",
+		"# TYPE shop_db_open_connections gauge",
+		"# TYPE shop_sessions gauge",
+		"# TYPE shop_logins_failed_total counter",
+	} {
+		if _, ok := samples[line]; !ok {
+			t.Errorf("missing %q", line)
+		}
+	}
+
+	// the database isn't connected in tests, so its gauges have no sample
+	if _, ok := samples["shop_db_open_connections"]; ok {
+		t.Error("shop_db_open_connections reported without a database")
+	}
+
+	if got := samples["shop_sessions"]; got != "0" {
+		t.Errorf("shop_sessions = %q, want 0", got)
+	}
+}
@@ -1,52 +1,136 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/20        Yusan Kurban
- */
-
-package handler
-
-import (
-	"github.com/labstack/echo"
-
-	"ShopApi/general"
-	"ShopApi/general/errcode"
-	"ShopApi/utility"
-	"fmt"
-)
-
-func MustLogin(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		sess := utility.GlobalSessions.SessionStart(c.Response().Writer, c.Request())
-		id := sess.Get(general.SessionUserID)
-		fmt.Println(id)
-		if id == nil {
-			return general.NewErrorWithMessage(errcode.ErrLoginRequired, "User Must Login.")
-		}
-
-		return next(c)
-	}
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/20        Yusan Kurban
+ */
+
+package handler
+
+import (
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/models"
+	"ShopApi/utility"
+)
+
+const identityKey = "identity"
+
+// Identity is the authenticated caller of a request. SessionID is the
+// usersession row of the login, Method is general.AuthSession or
+// general.AuthToken.
+type Identity struct {
+	UserID    uint64
+	SessionID string
+	Method    string
+}
+
+// MustLogin accepts either an "Authorization: Bearer" access token or a
+// login session, as long as the login wasn't revoked and the user isn't
+// suspended, and stores the caller's Identity in the context.
+func MustLogin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		auth := c.Request().Header.Get(echo.HeaderAuthorization)
+		if auth != "" {
+			if !strings.HasPrefix(auth, "Bearer ") {
+				return general.NewError(errcode.ErrLoginRequired)
+			}
+
+			claims, err := utility.ParseToken(strings.TrimPrefix(auth, "Bearer "))
+			if err != nil {
+				requestLog(c).Debug("Reject access token: %v", err)
+
+				return general.NewError(errcode.ErrLoginRequired)
+			}
+
+			if err = checkLogin(c, claims.SessionID, claims.UserID); err != nil {
+				return err
+			}
+
+			c.Set(identityKey, &Identity{UserID: claims.UserID, SessionID: claims.SessionID, Method: general.AuthToken})
+			touchSession(c, claims.SessionID, 0)
+
+			return next(c)
+		}
+
+		sess := utility.GlobalSessions.SessionStart(c.Response().Writer, c.Request())
+		id, ok := sess.Get(general.SessionUserID).(uint64)
+		if !ok {
+			return general.NewError(errcode.ErrLoginRequired)
+		}
+
+		loginID, _ := sess.Get(general.SessionLoginID).(string)
+		if err := checkLogin(c, loginID, id); err != nil {
+			return err
+		}
+
+		c.Set(identityKey, &Identity{UserID: id, SessionID: loginID, Method: general.AuthSession})
+		touchSession(c, loginID, utility.SessionLifetime())
+
+		return next(c)
+	}
+}
+
+// checkLogin refuses a login whose usersession row was revoked or has
+// expired, and the logins of a suspended user. Both are checked against
+// MySQL so that a revocation made by any instance is seen by all of them.
+func checkLogin(c echo.Context, sessionID string, userID uint64) error {
+	_, err := models.SessionService.Active(sessionID, userID)
+	if err == nil {
+		err = models.UserService.CheckActive(userID)
+	}
+
+	switch err {
+	case nil:
+		return nil
+	case gorm.ErrRecordNotFound:
+		return general.NewError(errcode.ErrLoginRequired)
+	case models.ErrUserSuspended:
+		return general.NewError(errcode.ErrAccountDisabled)
+	}
+
+	requestLog(c).Error("Mysql error:", err)
+
+	return general.NewError(errcode.ErrMysql)
+}
+
+// CurrentIdentity returns the caller set by MustLogin, or nil on routes
+// that don't require login.
+func CurrentIdentity(c echo.Context) *Identity {
+	identity, _ := c.Get(identityKey).(*Identity)
+
+	return identity
+}
+
+// currentUserID returns the ID of the caller. It must only be used behind
+// MustLogin.
+func currentUserID(c echo.Context) uint64 {
+	return CurrentIdentity(c).UserID
+}
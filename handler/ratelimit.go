@@ -0,0 +1,114 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+)
+
+const idleBucket = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket per client IP. A zero rate disables it.
+type rateLimiter struct {
+	lock    sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+var limiter = &rateLimiter{buckets: make(map[string]*bucket)}
+
+// SetRateLimit allows each client rps requests per second with bursts of up
+// to burst requests. rps <= 0 turns rate limiting off.
+func SetRateLimit(rps float64, burst int) {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	if limiter.rate != rps || limiter.burst != float64(burst) {
+		limiter.buckets = make(map[string]*bucket)
+	}
+
+	limiter.rate = rps
+	limiter.burst = float64(burst)
+}
+
+func (rl *rateLimiter) allow(key string, now time.Time) bool {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	if rl.rate <= 0 {
+		return true
+	}
+
+	if now.Sub(rl.swept) > idleBucket {
+		for k, b := range rl.buckets {
+			if now.Sub(b.last) > idleBucket {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.swept = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
+
+func RateLimit(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !limiter.allow(clientIP(c), time.Now()) {
+			return general.NewError(errcode.ErrTooManyRequests)
+		}
+
+		return next(c)
+	}
+}
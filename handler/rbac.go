@@ -0,0 +1,330 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/models"
+)
+
+// RequirePermission refuses callers of the admin API whose roles don't grant
+// perm, and audits the refusal. It must come after MustAdmin.
+func RequirePermission(perm string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := checkPermission(c, perm); err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// checkPermission returns ErrPermissionDenied, after auditing it, when the
+// caller lacks perm.
+func checkPermission(c echo.Context, perm string) error {
+	ok, err := adminCan(c, perm)
+	if err != nil {
+		requestLog(c).Error("Load permissions with error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	if !ok {
+		audit(c, c.Request().Method+" "+c.Path(), perm, models.AuditDenied, "")
+		permissionsDenied.With(perm).Inc()
+
+		return general.NewError(errcode.ErrPermissionDenied)
+	}
+
+	return nil
+}
+
+// checkEscalation refuses, after auditing it, a caller without PermAll
+// handing out role or perms when they lead to every permission: the super
+// admin role, and role:manage with which any role can be granted.
+func checkEscalation(c echo.Context, role string, perms []string) error {
+	if role != general.RoleSuper && !general.Grants(perms, general.PermRoleManage) {
+		return nil
+	}
+
+	return checkPermission(c, general.PermAll)
+}
+
+// adminCan reports whether the roles of the caller grant perm.
+func adminCan(c echo.Context, perm string) (bool, error) {
+	identity := CurrentAdmin(c)
+	if identity == nil {
+		return false, nil
+	}
+
+	if identity.permissions == nil {
+		perms, err := models.RoleService.Permissions(identity.AdminID)
+		if err != nil {
+			return false, err
+		}
+		identity.permissions = append([]string{}, perms...)
+	}
+
+	return general.Grants(identity.permissions, perm), nil
+}
+
+// audit records an action of the caller. A failure to record it is logged
+// but doesn't fail the request.
+func audit(c echo.Context, action, perm, result, detail string) {
+	a := &models.AdminAudit{
+		Action:     action,
+		Permission: perm,
+		Result:     result,
+		Detail:     detail,
+		IP:         clientIP(c),
+		RequestID:  CurrentRequestID(c),
+	}
+	if identity := CurrentAdmin(c); identity != nil {
+		a.AdminID = identity.AdminID
+	}
+
+	requestLog(c).Warn("Audit: admin %d %s %s (%s) %s", a.AdminID, result, action, perm, detail)
+
+	if err := models.AuditService.Record(a); err != nil {
+		requestLog(c).Error("Record audit with error:", err)
+	}
+}
+
+func GetRoles(c echo.Context) error {
+	roles, err := models.RoleService.List()
+	if err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	return general.Respond(c, roles)
+}
+
+func CreateRole(c echo.Context) error {
+	var (
+		err error
+		req models.CreateRole
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	name := strings.TrimSpace(req.Name)
+
+	if err = checkEscalation(c, name, req.Permissions); err != nil {
+		return err
+	}
+
+	_, err = models.RoleService.GetByName(name)
+	if err == nil {
+		return general.NewError(errcode.ErrRoleExists)
+	}
+	if err != gorm.ErrRecordNotFound {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	r := &models.Role{
+		Name:        name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+
+	if err = models.RoleService.Create(r); err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	audit(c, "role:create", general.PermRoleManage, models.AuditGranted,
+		fmt.Sprintf("role %d %s: %s", r.ID, r.Name, strings.Join(r.Permissions, " ")))
+
+	return general.Respond(c, r)
+}
+
+func ChangeRole(c echo.Context) error {
+	var (
+		err error
+		req models.ChangeRole
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if err = checkEscalation(c, "", req.Permissions); err != nil {
+		return err
+	}
+
+	if err = models.RoleService.Change(req.ID, req.Description, req.Permissions); err != nil {
+		return roleError(c, err)
+	}
+
+	audit(c, "role:change", general.PermRoleManage, models.AuditGranted,
+		fmt.Sprintf("role %d: %s", req.ID, strings.Join(req.Permissions, " ")))
+
+	return general.Respond(c, nil)
+}
+
+func DeleteRole(c echo.Context) error {
+	var (
+		err error
+		req models.ConRole
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if err = models.RoleService.Delete(req.ID); err != nil {
+		return roleError(c, err)
+	}
+
+	audit(c, "role:delete", general.PermRoleManage, models.AuditGranted, fmt.Sprintf("role %d", req.ID))
+
+	return general.Respond(c, nil)
+}
+
+func AssignRole(c echo.Context) error {
+	var (
+		err error
+		req models.AssignRole
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if _, err = models.AdminService.GetByID(req.AdminID); err != nil {
+		return roleError(c, err)
+	}
+
+	r, err := models.RoleService.GetByID(req.RoleID)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	if err = checkEscalation(c, r.Name, r.Permissions); err != nil {
+		return err
+	}
+
+	if err = models.RoleService.Assign(req.AdminID, req.RoleID); err != nil {
+		return roleError(c, err)
+	}
+
+	audit(c, "role:assign", general.PermRoleManage, models.AuditGranted,
+		fmt.Sprintf("role %d to admin %d", req.RoleID, req.AdminID))
+
+	return general.Respond(c, nil)
+}
+
+func UnassignRole(c echo.Context) error {
+	var (
+		err error
+		req models.AssignRole
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	r, err := models.RoleService.GetByID(req.RoleID)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	if err = checkEscalation(c, r.Name, r.Permissions); err != nil {
+		return err
+	}
+
+	if err = models.RoleService.Unassign(req.AdminID, req.RoleID); err != nil {
+		return roleError(c, err)
+	}
+
+	audit(c, "role:unassign", general.PermRoleManage, models.AuditGranted,
+		fmt.Sprintf("role %d from admin %d", req.RoleID, req.AdminID))
+
+	return general.Respond(c, nil)
+}
+
+func GetAdminRoles(c echo.Context) error {
+	var (
+		err error
+		req models.ConAdminRoles
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	roles, err := models.RoleService.AdminRoles(req.AdminID)
+	if err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	return general.Respond(c, roles)
+}
+
+// roleError maps the errors of RoleService to responses.
+func roleError(c echo.Context, err error) error {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		return general.NewError(errcode.ErrNotFound)
+	case models.ErrBuiltinRole:
+		return general.NewError(errcode.ErrBuiltinRole)
+	case models.ErrLastSuperAdmin:
+		return general.NewError(errcode.ErrLastSuperAdmin)
+	}
+
+	requestLog(c).Error("Mysql error:", err)
+
+	return general.NewError(errcode.ErrMysql)
+}
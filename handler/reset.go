@@ -0,0 +1,111 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/models"
+	"ShopApi/sms"
+)
+
+type ResetTokenResp struct {
+	ResetToken string `json:"reset_token"`
+	ExpiresIn  int64  `json:"expires_in"`
+}
+
+// VerifyResetCode exchanges a code sent for the reset purpose for a short
+// lived reset token. A phone that isn't registered fails like a wrong code.
+func VerifyResetCode(c echo.Context) error {
+	var (
+		err error
+		req models.VerifyReset
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if err = verifySMSCode(c, req.Mobile, sms.PurposeReset, req.Code); err != nil {
+		return err
+	}
+
+	token, err := models.UserService.StartReset(req.Mobile)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return general.NewError(errcode.ErrInvalidSMSCode)
+		}
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	return general.Respond(c, ResetTokenResp{
+		ResetToken: token,
+		ExpiresIn:  int64(models.ResetTokenTTL.Seconds()),
+	})
+}
+
+// ResetPassword sets a new password with a reset token and logs the account
+// out everywhere.
+func ResetPassword(c echo.Context) error {
+	var (
+		err error
+		req models.ResetPassword
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	userID, err := models.UserService.ResetPassword(req.Token, *req.NewPass)
+	if err != nil {
+		if err == models.ErrResetTokenInvalid {
+			return general.NewError(errcode.ErrInvalidResetToken)
+		}
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	sessions, err := models.SessionService.RevokeAll(userID, "")
+	if err != nil {
+		requestLog(c).Error("Revoke sessions with error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+	endSessions(sessions...)
+
+	requestLog(c).Info("User %d reset the password", userID)
+
+	return general.Respond(c, nil)
+}
@@ -0,0 +1,254 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/models"
+	"ShopApi/server/initcache"
+	"ShopApi/utility"
+)
+
+const (
+	maxDeviceLength = 128
+	touchInterval   = time.Minute
+)
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type SessionReq struct {
+	ID string `json:"id" validate:"required"`
+}
+
+// newLogin describes a login of userID from the device of the request.
+func newLogin(c echo.Context, userID uint64, kind, device string) *models.UserSession {
+	if device == "" {
+		device = c.Request().UserAgent()
+	}
+	if len(device) > maxDeviceLength {
+		device = device[:maxDeviceLength]
+	}
+
+	s := &models.UserSession{
+		ID:     utility.NewSessionID(),
+		UserID: userID,
+		Kind:   kind,
+		Device: device,
+		IP:     clientIP(c),
+	}
+
+	if kind == general.AuthToken {
+		s.Expires = time.Now().Add(utility.RefreshTokenTTL())
+	} else {
+		s.Expires = time.Now().Add(utility.SessionLifetime())
+	}
+
+	return s
+}
+
+// startLogin logs userID in, with access and refresh tokens in the response
+// when token is set and with a session cookie otherwise.
+func startLogin(c echo.Context, userID uint64, token bool, device string) error {
+	if token {
+		resp, err := issueTokens(newLogin(c, userID, general.AuthToken, device))
+		if err != nil {
+			requestLog(c).Error("Mysql error:", err)
+
+			return general.NewError(errcode.ErrMysql)
+		}
+
+		return general.Respond(c, resp)
+	}
+
+	sess := utility.GlobalSessions.SessionStart(c.Response().Writer, c.Request())
+
+	login := newLogin(c, userID, general.AuthSession, device)
+	login.CookieID = sess.SessionID()
+	if err := models.SessionService.Create(login); err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	sess.Set(general.SessionUserID, userID)
+	sess.Set(general.SessionLoginID, login.ID)
+
+	return general.Respond(c, nil)
+}
+
+// issueTokens creates the usersession row of a token login and returns its
+// access and refresh tokens.
+func issueTokens(s *models.UserSession) (*TokenResp, error) {
+	refresh, hash := utility.NewRefreshToken(s.ID)
+	s.RefreshHash = hash
+
+	if err := models.SessionService.Create(s); err != nil {
+		return nil, err
+	}
+
+	return newTokenResp(s.UserID, s.ID, refresh), nil
+}
+
+func newTokenResp(userID uint64, sid, refresh string) *TokenResp {
+	token, claims := utility.NewToken(userID, sid)
+
+	return &TokenResp{
+		AccessToken:  token,
+		TokenType:    "Bearer",
+		ExpiresIn:    claims.ExpiresAt - claims.IssuedAt,
+		RefreshToken: refresh,
+	}
+}
+
+// endSessions makes revoked sessions unusable right away instead of when
+// their cookie or access token expires.
+func endSessions(sessions ...models.UserSession) {
+	for _, s := range sessions {
+		if s.CookieID != "" {
+			utility.DestroySession(s.CookieID)
+		}
+
+		utility.RevokeToken(s.ID)
+	}
+}
+
+// touchSession updates the last seen time of a login at most once per
+// touchInterval.
+func touchSession(c echo.Context, sid string, extend time.Duration) {
+	if sid == "" || initcache.Bm.IsExist("seen:"+sid) {
+		return
+	}
+
+	initcache.Bm.Put("seen:"+sid, true, touchInterval)
+
+	if err := models.SessionService.Touch(sid, extend); err != nil {
+		requestLog(c).Error("Touch session with error:", err)
+	}
+}
+
+func RefreshToken(c echo.Context) error {
+	var (
+		err error
+		req RefreshReq
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	sid, hash, err := utility.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		return general.NewError(errcode.ErrLoginRequired)
+	}
+
+	refresh, newHash := utility.NewRefreshToken(sid)
+	s, err := models.SessionService.Rotate(sid, hash, newHash, clientIP(c), time.Now().Add(utility.RefreshTokenTTL()))
+	if err != nil {
+		if err == models.ErrRefreshTokenReused {
+			requestLog(c).Warn("Refresh token of session %s reused, session revoked", sid)
+			endSessions(*s)
+
+			return general.NewError(errcode.ErrLoginRequired)
+		}
+
+		if err == gorm.ErrRecordNotFound {
+			return general.NewError(errcode.ErrLoginRequired)
+		}
+
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	return general.Respond(c, newTokenResp(s.UserID, s.ID, refresh))
+}
+
+func GetSessions(c echo.Context) error {
+	identity := CurrentIdentity(c)
+
+	list, err := models.SessionService.ListActive(identity.UserID)
+	if err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	for i := range list {
+		list[i].Current = list[i].ID == identity.SessionID
+	}
+
+	return general.Respond(c, list)
+}
+
+func RevokeSession(c echo.Context) error {
+	var (
+		err error
+		req SessionReq
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	s, err := models.SessionService.Revoke(currentUserID(c), req.ID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return general.NewError(errcode.ErrNotFound)
+		}
+
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	endSessions(*s)
+
+	return general.Respond(c, nil)
+}
+
+func RevokeAllSessions(c echo.Context) error {
+	list, err := models.SessionService.RevokeAll(currentUserID(c), "")
+	if err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	endSessions(list...)
+
+	return general.Respond(c, nil)
+}
@@ -0,0 +1,180 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"context"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/log"
+	"ShopApi/models"
+	"ShopApi/sms"
+)
+
+// sendTimeout bounds the delivery of codes sent in the background.
+const sendTimeout = 10 * time.Second
+
+// SMSSent tells the client how long the code stays valid and how long to
+// wait before asking for another one, in seconds.
+type SMSSent struct {
+	ExpiresIn  int `json:"expiresin"`
+	RetryAfter int `json:"retryafter"`
+}
+
+// SendSMS sends a verification code for a purpose to a phone.
+func SendSMS(c echo.Context) error {
+	var (
+		err error
+		req models.SendSMS
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if !sms.Enabled() {
+		return general.NewError(errcode.ErrSMSUnavailable)
+	}
+
+	code, err := models.SMSService.Issue(req.Phone, req.Purpose, clientIP(c))
+	if err != nil {
+		if err == models.ErrSMSTooFrequent {
+			smsCodes.With(req.Purpose, "throttled").Inc()
+
+			return general.NewError(errcode.ErrSMSTooFrequent)
+		}
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	if req.Purpose == sms.PurposeReset {
+		// Answer alike whether the phone is registered or not: reset codes
+		// are delivered in the background, to registered phones only.
+		go sendResetCode(requestLog(c), code.Message())
+	} else {
+		if err = sms.Send(c.Request().Context(), code.Message()); err != nil {
+			smsCodes.With(req.Purpose, "failed").Inc()
+			requestLog(c).Error("Send SMS with error:", err)
+
+			return general.NewError(errcode.ErrSMSUnavailable)
+		}
+
+		smsCodes.With(req.Purpose, "sent").Inc()
+	}
+
+	limits := sms.CurrentLimits()
+
+	return general.Respond(c, SMSSent{
+		ExpiresIn:  int(limits.CodeTTL.Seconds()),
+		RetryAfter: int(limits.Interval.Seconds()),
+	})
+}
+
+func sendResetCode(l *log.RecordLog, m *sms.Message) {
+	registered, err := models.UserService.PhoneRegistered(m.Phone)
+	if err != nil {
+		l.Error("Mysql error:", err)
+
+		return
+	}
+	if !registered {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	if err = sms.Send(ctx, m); err != nil {
+		smsCodes.With(m.Purpose, "failed").Inc()
+		l.Error("Send SMS with error:", err)
+
+		return
+	}
+
+	smsCodes.With(m.Purpose, "sent").Inc()
+}
+
+// SMSLogin logs in with a code sent to the phone, creating an account on
+// first login.
+func SMSLogin(c echo.Context) error {
+	var (
+		err error
+		req models.SMSLogin
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if err = verifySMSCode(c, req.Mobile, sms.PurposeLogin, req.Code); err != nil {
+		loginsFailed.With("smscode").Inc()
+
+		return err
+	}
+
+	u, created, err := models.UserService.PhoneLogin(req.Mobile)
+	if err != nil {
+		if err == models.ErrUserSuspended {
+			loginsFailed.With("suspended").Inc()
+
+			return general.NewError(errcode.ErrAccountDisabled)
+		}
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	if created {
+		requestLog(c).Info("User %d created by SMS login", u.UserID)
+	}
+
+	return startLogin(c, u.UserID, req.Token, req.Device)
+}
+
+// verifySMSCode consumes the code of phone for purpose.
+func verifySMSCode(c echo.Context, phone, purpose, code string) error {
+	err := models.SMSService.Verify(phone, purpose, code)
+	switch err {
+	case nil:
+		return nil
+	case models.ErrSMSCodeInvalid:
+		smsCodes.With(purpose, "rejected").Inc()
+
+		return general.NewError(errcode.ErrInvalidSMSCode)
+	}
+
+	requestLog(c).Error("Mysql error:", err)
+
+	return general.NewError(errcode.ErrMysql)
+}
@@ -0,0 +1,135 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/models"
+)
+
+// SuspendUser suspends a user, until a time or for good, and ends its
+// sessions.
+func SuspendUser(c echo.Context) error {
+	var (
+		err error
+		req models.SuspendUser
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return general.NewError(errcode.ErrInvalidSuspension)
+	}
+
+	if err = changeUserStatus(c, req.UserID, general.UserInactive, req.Reason, req.Until); err != nil {
+		return err
+	}
+
+	sessions, err := models.SessionService.RevokeAll(req.UserID, "")
+	if err != nil {
+		requestLog(c).Error("Revoke sessions with error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+	endSessions(sessions...)
+
+	detail := fmt.Sprintf("user %d: %s", req.UserID, req.Reason)
+	if req.Until != nil {
+		detail += ", until " + req.Until.Format(time.RFC3339)
+	}
+	audit(c, "user:suspend", general.PermUserManage, models.AuditGranted, detail)
+
+	return general.Respond(c, nil)
+}
+
+func ReactivateUser(c echo.Context) error {
+	var (
+		err error
+		req models.ReactivateUser
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	if err = changeUserStatus(c, req.UserID, general.UserActive, req.Reason, nil); err != nil {
+		return err
+	}
+
+	audit(c, "user:reactivate", general.PermUserManage, models.AuditGranted,
+		fmt.Sprintf("user %d: %s", req.UserID, req.Reason))
+
+	return general.Respond(c, nil)
+}
+
+func GetUserStatusHistory(c echo.Context) error {
+	var (
+		err error
+		req models.ConUserHistory
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	list, err := models.UserService.StatusHistory(req.UserID)
+	if err != nil {
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	return general.Respond(c, list)
+}
+
+func changeUserStatus(c echo.Context, userID uint64, status uint16, reason string, until *time.Time) error {
+	err := models.UserService.SetStatus(userID, status, reason, until, CurrentAdmin(c).AdminID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return general.NewError(errcode.ErrNotFound)
+		}
+
+		requestLog(c).Error("Change user status with error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	return nil
+}
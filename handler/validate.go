@@ -0,0 +1,91 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"math"
+	"reflect"
+
+	"gopkg.in/go-playground/validator.v9"
+
+	"ShopApi/general"
+	"ShopApi/openapi"
+	"ShopApi/sms"
+	"ShopApi/utility"
+)
+
+// maxPrice bounds prices so that they fit the decimal columns of the
+// database.
+const maxPrice = 1e8
+
+func init() {
+	general.RegisterValidation("mobile", func(fl validator.FieldLevel) bool {
+		return utility.IsValidPhone(fl.Field().String())
+	}, "{0}必须是有效的手机号", "{0} must be a valid mobile number")
+
+	general.RegisterValidation("price", isValidPrice,
+		"{0}必须是大于 0 且最多两位小数的金额", "{0} must be a positive amount with at most two decimals")
+
+	general.RegisterValidation("permission", func(fl validator.FieldLevel) bool {
+		return general.IsPermission(fl.Field().String())
+	}, "{0}不是有效的权限", "{0} must be a known permission")
+
+	general.RegisterValidation("smspurpose", func(fl validator.FieldLevel) bool {
+		return sms.IsPurpose(fl.Field().String())
+	}, "{0}不是有效的验证码用途", "{0} must be one of register, login, reset, rebind")
+
+	openapi.RegisterTag("mobile", func(s *openapi.Schema) {
+		s.Pattern = utility.PhonePattern
+	})
+	openapi.RegisterTag("price", func(s *openapi.Schema) {
+		lower, upper := 0.0, float64(maxPrice)
+		s.Minimum, s.ExclusiveMinimum = &lower, true
+		s.Maximum, s.ExclusiveMaximum = &upper, true
+		s.MultipleOf = 0.01
+	})
+	openapi.RegisterTag("permission", func(s *openapi.Schema) {
+		s.Enum = general.Permissions
+	})
+	openapi.RegisterTag("smspurpose", func(s *openapi.Schema) {
+		s.Enum = sms.Purposes
+	})
+}
+
+// isValidPrice accepts positive amounts in yuan with at most two decimals.
+func isValidPrice(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.Float32 && field.Kind() != reflect.Float64 {
+		return false
+	}
+
+	price := field.Float()
+	if price <= 0 || price >= maxPrice {
+		return false
+	}
+
+	cents := price * 100
+
+	return math.Abs(cents-math.Round(cents)) < 1e-6
+}
@@ -0,0 +1,75 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	HeaderAPIVersion  = "X-API-Version"
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+	HeaderLink        = "Link"
+)
+
+// APIVersion tags the responses of a version group with its version.
+func APIVersion(version string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().Header().Set(HeaderAPIVersion, version)
+
+			return next(c)
+		}
+	}
+}
+
+// Deprecated marks the routes under prefix as deprecated since the given
+// time and removed at sunset, and links each to the same route under
+// successor. The headers follow RFC 9745 and RFC 8594.
+func Deprecated(prefix, successor string, since, sunset time.Time) echo.MiddlewareFunc {
+	deprecation := "@" + strconv.FormatInt(since.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, deprecation)
+			header.Set(HeaderSunset, sunsetDate)
+			if strings.HasPrefix(c.Path(), prefix) {
+				header.Set(HeaderLink, "<"+successor+strings.TrimPrefix(c.Path(), prefix)+`>; rel="successor-version"`)
+			}
+
+			deprecatedRequests.With(c.Path()).Inc()
+
+			return next(c)
+		}
+	}
+}
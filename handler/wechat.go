@@ -0,0 +1,177 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package handler
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/general/errcode"
+	"ShopApi/models"
+	"ShopApi/wechat"
+)
+
+// WechatLogin logs in with the code of the WeChat OAuth page, creating an
+// account on first login.
+func WechatLogin(c echo.Context) error {
+	var (
+		err error
+		req models.WechatLogin
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	id, err := exchangeWechatCode(c, req.Code)
+	if err != nil {
+		return err
+	}
+
+	u, created, err := models.UserService.WechatLogin(id.OpenID, id.Nickname, id.Avatar)
+	if err != nil {
+		if err == models.ErrUserSuspended {
+			loginsFailed.With("suspended").Inc()
+
+			return general.NewError(errcode.ErrAccountDisabled)
+		}
+		requestLog(c).Error("Mysql error:", err)
+
+		return general.NewError(errcode.ErrMysql)
+	}
+
+	if created {
+		requestLog(c).Info("User %d created by WeChat login", u.UserID)
+	}
+
+	return startLogin(c, u.UserID, req.Token, req.Device)
+}
+
+// BindWechat binds the WeChat identity of a code to the caller.
+func BindWechat(c echo.Context) error {
+	var (
+		err error
+		req models.WechatCode
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	id, err := exchangeWechatCode(c, req.Code)
+	if err != nil {
+		return err
+	}
+
+	if err = models.UserService.BindWechat(currentUserID(c), id.OpenID); err != nil {
+		return wechatError(c, err)
+	}
+
+	return general.Respond(c, nil)
+}
+
+func UnbindWechat(c echo.Context) error {
+	if err := models.UserService.UnbindWechat(currentUserID(c)); err != nil {
+		return wechatError(c, err)
+	}
+
+	return general.Respond(c, nil)
+}
+
+// MergeWechat moves the account created by logging in with the WeChat
+// identity of a code into the caller, which then logs in with both.
+func MergeWechat(c echo.Context) error {
+	var (
+		err error
+		req models.WechatCode
+	)
+
+	if err = general.BindAndValidate(c, &req); err != nil {
+		requestLog(c).Error("Bind with error:", err)
+
+		return err
+	}
+
+	id, err := exchangeWechatCode(c, req.Code)
+	if err != nil {
+		return err
+	}
+
+	userID := currentUserID(c)
+
+	merged, err := models.UserService.MergeWechat(userID, id.OpenID)
+	if err != nil {
+		return wechatError(c, err)
+	}
+
+	sessions, err := models.SessionService.RevokeAll(merged, "")
+	if err != nil {
+		requestLog(c).Error("Revoke sessions with error:", err)
+	}
+	endSessions(sessions...)
+
+	requestLog(c).Info("User %d merged into user %d", merged, userID)
+
+	return general.Respond(c, nil)
+}
+
+func exchangeWechatCode(c echo.Context, code string) (*wechat.Identity, error) {
+	id, err := wechat.Exchange(c.Request().Context(), code)
+	switch err {
+	case nil:
+		return id, nil
+	case wechat.ErrUnavailable:
+		return nil, general.NewError(errcode.ErrWechatUnavailable)
+	case wechat.ErrInvalidCode:
+		loginsFailed.With("wechatcode").Inc()
+
+		return nil, general.NewError(errcode.ErrInvalidWechatCode)
+	}
+
+	requestLog(c).Error("Exchange WeChat code with error:", err)
+
+	return nil, general.NewError(errcode.ErrNoConnection)
+}
+
+// wechatError maps the errors of binding and merging to responses.
+func wechatError(c echo.Context, err error) error {
+	switch err {
+	case models.ErrWechatBound:
+		return general.NewError(errcode.ErrWechatBound)
+	case models.ErrWechatUnbind:
+		return general.NewError(errcode.ErrWechatUnbind)
+	case models.ErrNothingToMerge, gorm.ErrRecordNotFound:
+		return general.NewError(errcode.ErrNotFound)
+	}
+
+	requestLog(c).Error("Mysql error:", err)
+
+	return general.NewError(errcode.ErrMysql)
+}
@@ -1,72 +1,206 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/18        Yusan Kurban
- */
-package log
-
-import (
-	"fmt"
-	"log"
-
-	"go.uber.org/zap"
-)
-
-// RecordLog is recording log
-type RecordLog struct{}
-
-var (
-	Logger *RecordLog
-	zapLog *zap.Logger
-)
-
-func init() {
-	Logger = &RecordLog{}
-	zapLog, _ = zap.NewDevelopment()
-}
-
-func (l *RecordLog) Error(desc string, err error) {
-	zapLog.Error(desc, zap.Error(err))
-}
-
-func (l *RecordLog) Debug(format string, a ...interface{}) {
-	info := fmt.Sprintf(format, a)
-	zapLog.Debug(info, zap.Skip())
-}
-
-func (l *RecordLog) Fatal(v ...interface{}) {
-	log.Fatal(v)
-}
-
-func (l *RecordLog) Info(format string, a ...interface{}) {
-	info := fmt.Sprintf(format, a)
-	zapLog.Info(info, zap.Skip())
-}
-
-func (l *RecordLog) Warn(format string, a ...interface{}) {
-	warn := fmt.Sprintf(format, a)
-	zapLog.Warn(warn, zap.Skip())
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/18        Yusan Kurban
+ */
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config selects where and how logs are written.
+type Config struct {
+	Level string
+	// Encoding is "console" or "json".
+	Encoding string
+	// Output is "stdout", "stderr" or the path of a log file.
+	Output string
+	// MaxSize rotates the file once it grows beyond MaxSize megabytes, and
+	// RotateEvery rotates it on every multiple of the interval. Zero
+	// disables either. MaxBackups bounds the rotated files kept.
+	MaxSize     int
+	RotateEvery time.Duration
+	MaxBackups  int
+}
+
+// RecordLog is recording log
+type RecordLog struct {
+	fields []zapcore.Field
+}
+
+var (
+	Logger *RecordLog
+	zapLog atomic.Value
+	level  = zap.NewAtomicLevelAt(zap.DebugLevel)
+)
+
+func init() {
+	Logger = &RecordLog{}
+
+	conf := zap.NewDevelopmentConfig()
+	conf.Level = level
+	l, _ := conf.Build(zap.AddCallerSkip(2))
+	zapLog.Store(l)
+}
+
+// Init replaces the logger with one built from conf.
+func Init(conf Config) error {
+	if err := SetLevel(conf.Level); err != nil {
+		return err
+	}
+
+	var encoder zapcore.Encoder
+	switch conf.Encoding {
+	case "json":
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	default:
+		return fmt.Errorf("log: unknown encoding %q", conf.Encoding)
+	}
+
+	var out zapcore.WriteSyncer
+	switch conf.Output {
+	case "", "stderr":
+		out = zapcore.Lock(os.Stderr)
+	case "stdout":
+		out = zapcore.Lock(os.Stdout)
+	default:
+		w, err := newRotateWriter(conf.Output, conf.MaxSize, conf.RotateEvery, conf.MaxBackups)
+		if err != nil {
+			return err
+		}
+		out = w
+	}
+
+	l := zap.New(zapcore.NewCore(encoder, out, level), zap.AddCaller(), zap.AddCallerSkip(2), zap.ErrorOutput(zapcore.Lock(os.Stderr)))
+	old := current()
+	zapLog.Store(l)
+	old.Sync()
+
+	return nil
+}
+
+// Sync flushes buffered logs.
+func Sync() error {
+	return current().Sync()
+}
+
+func current() *zap.Logger {
+	return zapLog.Load().(*zap.Logger)
+}
+
+// IsValidLevel reports whether l names a level such as "debug" or "warn".
+func IsValidLevel(l string) bool {
+	var lvl zapcore.Level
+
+	return lvl.UnmarshalText([]byte(l)) == nil
+}
+
+// IsValidEncoding reports whether e is an encoding accepted by Init.
+func IsValidEncoding(e string) bool {
+	return e == "json" || e == "console"
+}
+
+// SetLevel changes the minimum enabled level at runtime.
+func SetLevel(l string) error {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(l)); err != nil {
+		return err
+	}
+
+	level.SetLevel(lvl)
+
+	return nil
+}
+
+// Lazy is a field whose value is computed each time an entry is written,
+// for values such as latency that change over the life of a child logger.
+func Lazy(key string, value func() string) zapcore.Field {
+	return zap.Stringer(key, lazyString(value))
+}
+
+type lazyString func() string
+
+func (s lazyString) String() string {
+	return s()
+}
+
+// With returns a child logger that adds fields to every entry.
+func (l *RecordLog) With(fields ...zapcore.Field) *RecordLog {
+	all := make([]zapcore.Field, 0, len(l.fields)+len(fields))
+	all = append(all, l.fields...)
+
+	return &RecordLog{fields: append(all, fields...)}
+}
+
+func (l *RecordLog) write(lvl zapcore.Level, msg string, fields []zapcore.Field) {
+	if ce := current().Check(lvl, msg); ce != nil {
+		all := make([]zapcore.Field, 0, len(l.fields)+len(fields))
+		all = append(all, l.fields...)
+		ce.Write(append(all, fields...)...)
+	}
+}
+
+func formatMessage(format string, a []interface{}) string {
+	if len(a) == 0 {
+		return format
+	}
+
+	return fmt.Sprintf(format, a...)
+}
+
+func (l *RecordLog) Error(desc string, err error) {
+	l.write(zapcore.ErrorLevel, desc, []zapcore.Field{zap.Error(err)})
+}
+
+func (l *RecordLog) Debug(format string, a ...interface{}) {
+	l.write(zapcore.DebugLevel, formatMessage(format, a), nil)
+}
+
+func (l *RecordLog) Fatal(v ...interface{}) {
+	l.write(zapcore.FatalLevel, fmt.Sprint(v...), nil)
+}
+
+func (l *RecordLog) Info(format string, a ...interface{}) {
+	l.write(zapcore.InfoLevel, formatMessage(format, a), nil)
+}
+
+func (l *RecordLog) Warn(format string, a ...interface{}) {
+	l.write(zapcore.WarnLevel, formatMessage(format, a), nil)
+}
+
+// Fields writes msg with structured fields at level lvl.
+func (l *RecordLog) Fields(lvl zapcore.Level, msg string, fields ...zapcore.Field) {
+	l.write(lvl, msg, fields)
+}
@@ -0,0 +1,163 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// rotateWriter is a log file that is renamed to <path>.<time> and reopened
+// when it grows too large or a new interval begins.
+type rotateWriter struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	every      time.Duration
+	maxBackups int
+
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func newRotateWriter(path string, maxSize int, every time.Duration, maxBackups int) (*rotateWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	w := &rotateWriter{
+		path:       path,
+		maxSize:    int64(maxSize) * 1024 * 1024,
+		every:      every,
+		maxBackups: maxBackups,
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *rotateWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.opened = info.ModTime()
+	if w.size == 0 {
+		w.opened = time.Now()
+	}
+
+	return nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.due(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *rotateWriter) Sync() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.file.Sync()
+}
+
+// due reports whether writing n more bytes needs a new file first.
+func (w *rotateWriter) due(n int) bool {
+	if w.size == 0 {
+		return false
+	}
+
+	if w.maxSize > 0 && w.size+int64(n) > w.maxSize {
+		return true
+	}
+
+	return w.every > 0 && !time.Now().Truncate(w.every).Equal(w.opened.Truncate(w.every))
+}
+
+func (w *rotateWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(w.path, w.path+"."+time.Now().Format(backupTimeFormat)); err != nil {
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.prune()
+
+	return nil
+}
+
+// prune removes the oldest backups beyond maxBackups.
+func (w *rotateWriter) prune() {
+	if w.maxBackups <= 0 {
+		return
+	}
+
+	backups, err := filepath.Glob(w.path + ".*")
+	if err != nil || len(backups) <= w.maxBackups {
+		return
+	}
+
+	// the time suffix sorts in chronological order
+	sort.Strings(backups)
+	for _, backup := range backups[:len(backups)-w.maxBackups] {
+		os.Remove(backup)
+	}
+}
@@ -0,0 +1,359 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default latency buckets in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry served by /metrics.
+var Default = NewRegistry()
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and writes them in the Prometheus text format.
+type Registry struct {
+	lock       sync.RWMutex
+	names      map[string]bool
+	collectors []collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, c collector) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+
+	r.names[name] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo writes every metric in the order they were registered.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.lock.RLock()
+	collectors := r.collectors
+	r.lock.RUnlock()
+
+	cw := &countingWriter{w: w}
+	buf := bufio.NewWriter(cw)
+	for _, c := range collectors {
+		c.write(buf)
+	}
+	err := buf.Flush()
+
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+
+	return n, err
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (d *desc) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// series formats name{labels}, with extra appended after the labels.
+func (d *desc) series(name string, values []string, extra ...string) string {
+	var pairs []string
+	for i, label := range d.labels {
+		pairs = append(pairs, label+`="`+escape(values[i])+`"`)
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escape(extra[i+1])+`"`)
+	}
+
+	if len(pairs) == 0 {
+		return name
+	}
+
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// key joins label values; \xff can't occur in valid UTF-8 label values.
+func key(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// vec keeps one child per combination of label values.
+type vec struct {
+	desc
+	lock     sync.RWMutex
+	children map[string]interface{}
+	values   map[string][]string
+}
+
+func (v *vec) child(values []string, create func() interface{}) interface{} {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	k := key(values)
+
+	v.lock.RLock()
+	c, ok := v.children[k]
+	v.lock.RUnlock()
+	if ok {
+		return c
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if c, ok = v.children[k]; !ok {
+		c = create()
+		v.children[k] = c
+		v.values[k] = append([]string(nil), values...)
+	}
+
+	return c
+}
+
+// each calls fn for every child in a stable order.
+func (v *vec) each(fn func(values []string, c interface{})) {
+	v.lock.RLock()
+	keys := make([]string, 0, len(v.children))
+	for k := range v.children {
+		keys = append(keys, k)
+	}
+	v.lock.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v.lock.RLock()
+		values, c := v.values[k], v.children[k]
+		v.lock.RUnlock()
+
+		fn(values, c)
+	}
+}
+
+func newVec(name, help, kind string, labels []string) vec {
+	return vec{
+		desc:     desc{name: name, help: help, kind: kind, labels: labels},
+		children: make(map[string]interface{}),
+		values:   make(map[string][]string),
+	}
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	n uint64
+}
+
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.n, 1)
+}
+
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.n, n)
+}
+
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.n)
+}
+
+// CounterVec is a set of counters partitioned by labels.
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec registers a counter with the given labels in Default.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels)}
+	Default.register(name, c)
+
+	return c
+}
+
+// NewCounter registers a counter without labels in Default.
+func NewCounter(name, help string) *Counter {
+	return NewCounterVec(name, help).With()
+}
+
+// With returns the counter of the label values, creating it if needed.
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.child(values, func() interface{} { return &Counter{} }).(*Counter)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w)
+	c.each(func(values []string, child interface{}) {
+		fmt.Fprintf(w, "%s %d\n", c.series(c.name, values), child.(*Counter).Value())
+	})
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	upper  []float64
+	counts []uint64
+	count  uint64
+	sum    uint64 // float64 bits
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.upper, v)
+	if i < len(h.counts) {
+		atomic.AddUint64(&h.counts[i], 1)
+	}
+
+	for {
+		old := atomic.LoadUint64(&h.sum)
+		sum := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(&h.sum, old, sum) {
+			break
+		}
+	}
+
+	atomic.AddUint64(&h.count, 1)
+}
+
+// HistogramVec is a set of histograms partitioned by labels.
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// NewHistogramVec registers a histogram with the given upper bounds, in
+// increasing order, and labels in Default.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+	Default.register(name, h)
+
+	return h
+}
+
+// With returns the histogram of the label values, creating it if needed.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.child(values, func() interface{} {
+		return &Histogram{upper: h.buckets, counts: make([]uint64, len(h.buckets))}
+	}).(*Histogram)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w)
+	h.each(func(values []string, child interface{}) {
+		hist := child.(*Histogram)
+
+		var cumulative uint64
+		for i, upper := range hist.upper {
+			cumulative += atomic.LoadUint64(&hist.counts[i])
+			fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", values, "le", formatFloat(upper)), cumulative)
+		}
+
+		count := atomic.LoadUint64(&hist.count)
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s %s\n", h.series(h.name+"_sum", values), formatFloat(math.Float64frombits(atomic.LoadUint64(&hist.sum))))
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_count", values), count)
+	})
+}
+
+// GaugeFunc is a gauge whose value is read on every scrape. Returning false
+// omits the sample, e.g. while the source isn't ready.
+type GaugeFunc struct {
+	desc
+	fn func() (float64, bool)
+}
+
+// NewGaugeFunc registers a gauge read from fn in Default.
+func NewGaugeFunc(name, help string, fn func() (float64, bool)) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help, kind: "gauge"}, fn: fn}
+	Default.register(name, g)
+
+	return g
+}
+
+// NewCounterFunc registers a counter read from fn in Default, for totals
+// kept elsewhere such as the wait count of a connection pool.
+func NewCounterFunc(name, help string, fn func() (float64, bool)) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help, kind: "counter"}, fn: fn}
+	Default.register(name, g)
+
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.header(w)
+	if v, ok := g.fn(); ok {
+		fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(v))
+	}
+}
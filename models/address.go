@@ -1,193 +1,216 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/18        Li Zebang
- *     Modify : 2017/07/20        Yu Yi
- *     Modify : 2017/07/20        Yang Zhengtian
- */
-
-package models
-
-import (
-	"time"
-
-	"ShopApi/orm"
-)
-
-type ContactServiceProvider struct {
-}
-
-var ContactService *ContactServiceProvider = &ContactServiceProvider{}
-
-type Contact struct {
-	ID        uint64    `sql:"auto_increment; primary_key;" json:"id"`
-	UserID    uint64    `gorm:"column:userid" json:"userid"`
-	Name      string    `json:"name"`
-	Phone     string    `json:"phone"`
-	Province  string    `json:"province"`
-	City      string    `json:"city"`
-	Street    string    `json:"street"`
-	Address   string    `json:"address"`
-	Created   time.Time `json:"created"`
-	IsDefault uint8     `gorm:"column:isdefault" json:"isdefault" `
-	Page       uint64    `json:"page" validate:"required,numeric"`
-	PageSize   uint64    `json:"pagesize" validate:"required,numeric"`
-}
-
-type OrmContact struct {
-	ID        uint64    `json:"id" validate:"required,numeric"`
-	UserID    uint64    `gorm:"column:userid" json:"userid"`
-	Name      string    `json:"name" validate:"required,alphanum,min=6,max=100"`
-	Phone     string    `json:"phone" validate:"required,numeric,min=6,max=20"`
-	Province  string    `json:"province" validate:"required,alphanum,min=6,max=100"`
-	City      string    `json:"city" validate:"required,alphanum,min=6,max=100"`
-	Street    string    `json:"street" validate:"required,alphanum,min=6,max=100"`
-	Address   string    `json:"address" validate:"required,alphanum,min=6,max=200"`
-	Created   time.Time `json:"created"`
-	IsDefault uint8     `json:"isdefault" validate:"required,numeric"`
-	Page       uint64    `json:"page" validate:"required,numeric"`
-	PageSize   uint64    `json:"pagesize" validate:"required,numeric"`
-}
-
-type AddressGet struct {
-	Province string `json:"province"`
-	City     string `json:"city"`
-	Street   string `json:"street"`
-	Address  string `json:"address"`
-}
-
-type ChangeAddress struct {
-	ID       uint64  `json:"id" validate:"numeric"`
-	Name     *string `json:"name" validate:"required, alphaunicode, min=2,max=18"`
-	Phone    *string `json:"phone" validate:"required, alphanum, min=6,max=30"`
-	Province *string `json:"province" validate:"required, alphaunicode, min=2,max=30"`
-	City     *string `json:"city" validate:"required, alphaunicode, min=2,max=30"`
-	Street   *string `json:"street" validate:"required, alphaunicode, min=2,max=30"`
-	Address  *string `json:"address" validate:"required, alphaunicode, min=2,max=30"`
-}
-
-func (Contact) TableName() string {
-	return "contact"
-}
-
-func (csp *ContactServiceProvider) AddAddress(ormContact *OrmContact) error {
-	ormContact.Created = time.Now()
-
-	contact := &Contact{
-		UserID:    ormContact.UserID,
-		Name:      ormContact.Name,
-		Phone:     ormContact.Phone,
-		Province:  ormContact.Province,
-		City:      ormContact.City,
-		Street:    ormContact.Street,
-		Address:   ormContact.Address,
-		Created:   time.Now(),
-		IsDefault: ormContact.IsDefault,
-	}
-
-	db := orm.Conn
-
-	return db.Create(contact).Error
-}
-
-func (csp *ContactServiceProvider) ChangeAddress(addr OrmContact) error {
-	var (
-		con Contact
-	)
-
-	changeMap := map[string]interface{}{
-		"name":     addr.Name,
-		"phone":    addr.Phone,
-		"province": addr.Province,
-		"city":     addr.City,
-		"street":   addr.Street,
-		"address":  addr.Address,
-	}
-
-	db := orm.Conn
-	err := db.Model(&con).Where("id = ?", addr.ID).Update(changeMap).Limit(1).Error
-
-	return err
-}
-
-func (csp *ContactServiceProvider) FindAddressId(ID uint64) error {
-	var (
-		con Contact
-	)
-
-	db := orm.Conn
-	err := db.Where("id = ?", ID).First(&con).Error
-
-	return err
-}
-
-func (csp *ContactServiceProvider) GetAddressByUerId(userId uint64, pageStart, pageEnd uint64) ([]AddressGet, error) {
-	var (
-		list   Contact
-		getAdd []AddressGet
-	)
-
-	db := orm.Conn
-	err := db.Where("userid =? ", userId).Find(&list).Error
-	if err != nil {
-		return getAdd, err
-	}
-	sql := "SELECT * FROM contact WHERE userid = ? LIMIT ?, ? LOCK IN SHARE MODE"
-
-	rows, err := db.Raw(sql, userId, pageStart, pageEnd).Rows()
-	defer rows.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	for rows.Next() {
-		db.ScanRows(rows, &list)
-			add := AddressGet{
-				Province: list.Province,
-				City:     list.City,
-				Street:   list.Street,
-				Address:  list.Address,
-			}
-			getAdd = append(getAdd, add)
-		}
-
-	return getAdd, nil
-}
-
-func (csp *ContactServiceProvider) AlterDefault(id uint64) error {
-	var (
-		s   Contact
-		con Contact
-	)
-	db := orm.Conn
-	err := db.Where("id=?", id).Find(&s).Error
-
-	updater := map[string]interface{}{"isdefault": s.IsDefault^1}
-
-	err = db.Model(&con).Where("id=?", id).Update(updater).Limit(1).Error
-
-		return err
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/18        Li Zebang
+ *     Modify : 2017/07/20        Yu Yi
+ *     Modify : 2017/07/20        Yang Zhengtian
+ */
+
+package models
+
+import (
+	"time"
+
+	"ShopApi/orm"
+	"ShopApi/utility"
+)
+
+type ContactServiceProvider struct {
+}
+
+var ContactService *ContactServiceProvider = &ContactServiceProvider{}
+
+type Contact struct {
+	ID        uint64    `sql:"auto_increment; primary_key;" json:"id" validate:"required"`
+	UserID    uint64    `gorm:"column:userid" json:"userid"`
+	Name      string    `json:"name"`
+	Phone     string    `json:"phone"`
+	Province  string    `json:"province"`
+	City      string    `json:"city"`
+	Street    string    `json:"street"`
+	Address   string    `json:"address"`
+	Created   time.Time `json:"created"`
+	IsDefault uint8     `gorm:"column:isdefault" json:"isdefault" `
+	Page       uint64    `json:"page"`
+	PageSize   uint64    `json:"pagesize" validate:"omitempty,max=100"`
+}
+
+type OrmContact struct {
+	ID        uint64    `json:"id"`
+	UserID    uint64    `gorm:"column:userid" json:"userid"`
+	Name      string    `json:"name" validate:"omitempty,max=100"`
+	Phone     string    `json:"phone" validate:"omitempty,mobile"`
+	Province  string    `json:"province" validate:"omitempty,max=100"`
+	City      string    `json:"city" validate:"omitempty,max=100"`
+	Street    string    `json:"street" validate:"omitempty,max=100"`
+	Address   string    `json:"address" validate:"omitempty,max=200"`
+	Created   time.Time `json:"created"`
+	IsDefault uint8     `json:"isdefault" validate:"omitempty,max=1"`
+	Page       uint64    `json:"page"`
+	PageSize   uint64    `json:"pagesize" validate:"omitempty,max=100"`
+	Cursor     string    `json:"cursor" validate:"omitempty,max=100"`
+}
+
+type AddressGet struct {
+	ID       uint64 `json:"id"`
+	Province string `json:"province"`
+	City     string `json:"city"`
+	Street   string `json:"street"`
+	Address  string `json:"address"`
+}
+
+// ChangeAddress is the new content of address ID.
+type ChangeAddress struct {
+	ID       uint64 `json:"id" validate:"required"`
+	Name     string `json:"name" validate:"omitempty,max=100"`
+	Phone    string `json:"phone" validate:"omitempty,mobile"`
+	Province string `json:"province" validate:"omitempty,max=100"`
+	City     string `json:"city" validate:"omitempty,max=100"`
+	Street   string `json:"street" validate:"omitempty,max=100"`
+	Address  string `json:"address" validate:"omitempty,max=200"`
+}
+
+func (Contact) TableName() string {
+	return "contact"
+}
+
+func (csp *ContactServiceProvider) AddAddress(ormContact *OrmContact) error {
+	ormContact.Created = time.Now()
+
+	contact := &Contact{
+		UserID:    ormContact.UserID,
+		Name:      ormContact.Name,
+		Phone:     ormContact.Phone,
+		Province:  ormContact.Province,
+		City:      ormContact.City,
+		Street:    ormContact.Street,
+		Address:   ormContact.Address,
+		Created:   time.Now(),
+		IsDefault: ormContact.IsDefault,
+	}
+
+	db := orm.Conn
+
+	return db.Create(contact).Error
+}
+
+// ChangeAddress changes the fields set in addr of address addr.ID of
+// userID. It returns gorm.ErrRecordNotFound when userID has no such address.
+func (csp *ContactServiceProvider) ChangeAddress(userID uint64, addr ChangeAddress) error {
+	var (
+		con Contact
+	)
+
+	changeMap := map[string]interface{}{
+		"name":     addr.Name,
+		"phone":    addr.Phone,
+		"province": addr.Province,
+		"city":     addr.City,
+		"street":   addr.Street,
+		"address":  addr.Address,
+	}
+
+	// fields left out of the request keep their value
+	for field, value := range changeMap {
+		if value == "" {
+			delete(changeMap, field)
+		}
+	}
+
+	return updateOwned(&con, userID, addr.ID, changeMap)
+}
+
+// GetAddressByUerId returns one page of the addresses of a user, newest
+// first, and the cursor of the next page, empty on the last one.
+func (csp *ContactServiceProvider) GetAddressByUerId(userId uint64, pager *utility.Pager) ([]AddressGet, string, error) {
+	var (
+		list     Contact
+		contacts []Contact
+		getAdd   []AddressGet
+		next     string
+	)
+
+	db := orm.Conn
+	clause, args := pager.Clause()
+	sql := "SELECT * FROM contact WHERE userid = ?" + clause + " LOCK IN SHARE MODE"
+
+	rows, err := db.Raw(sql, append([]interface{}{userId}, args...)...).Rows()
+	if err != nil {
+		return nil, next, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		db.ScanRows(rows, &list)
+		contacts = append(contacts, list)
+	}
+
+	if pager.More(len(contacts)) {
+		contacts = contacts[:pager.PageSize]
+		last := contacts[len(contacts)-1]
+		next = utility.Cursor{Created: last.Created, ID: last.ID}.String()
+	}
+
+	for _, con := range contacts {
+		add := AddressGet{
+			ID:       con.ID,
+			Province: con.Province,
+			City:     con.City,
+			Street:   con.Street,
+			Address:  con.Address,
+		}
+		getAdd = append(getAdd, add)
+	}
+
+	return getAdd, next, nil
+}
+
+// CountAddress returns how many addresses GetAddressByUerId pages through.
+func (csp *ContactServiceProvider) CountAddress(userId uint64) (uint64, error) {
+	var total uint64
+
+	err := orm.Conn.Model(&Contact{}).Where("userid = ?", userId).Count(&total).Error
+
+	return total, err
+}
+
+// AlterDefault switches whether address id of userID is the default one.
+// It returns gorm.ErrRecordNotFound when userID has no such address.
+func (csp *ContactServiceProvider) AlterDefault(userID, id uint64) error {
+	var (
+		s Contact
+	)
+
+	db := orm.Conn
+	err := db.Scopes(ownedBy(userID)).Where("id = ?", id).First(&s).Error
+	if err != nil {
+		return err
+	}
+
+	updater := map[string]interface{}{"isdefault": s.IsDefault ^ 1}
+
+	return db.Model(&s).Scopes(ownedBy(userID)).Update(updater).Error
+}
@@ -0,0 +1,209 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"time"
+
+	"ShopApi/general"
+	"ShopApi/orm"
+	"ShopApi/utility"
+)
+
+type AdminServiceProvider struct {
+}
+
+var AdminService *AdminServiceProvider = &AdminServiceProvider{}
+
+type Admin struct {
+	ID       uint64     `sql:"auto_increment;primary_key;" gorm:"column:id" json:"id"`
+	Username string     `json:"username"`
+	Password string     `json:"-"`
+	Email    string     `json:"email"`
+	Phone    string     `json:"phone"`
+	Name     string     `json:"name"`
+	Status   uint8      `json:"status"`
+	Created  time.Time  `json:"created"`
+	Updated  *time.Time `json:"updated"`
+}
+
+// AdminSession is one login of an admin. Admins authenticate with access
+// tokens only; the session lets a token be revoked before it expires.
+type AdminSession struct {
+	ID       string    `sql:"primary_key" gorm:"column:id" json:"id"`
+	AdminID  uint64    `gorm:"column:adminid" json:"-"`
+	Device   string    `json:"device"`
+	IP       string    `gorm:"column:ip" json:"ip"`
+	Status   uint8     `json:"-"`
+	Created  time.Time `json:"created"`
+	LastSeen time.Time `gorm:"column:lastseen" json:"lastseen"`
+	Expires  time.Time `json:"expires"`
+}
+
+type AdminLogin struct {
+	Username string `json:"username" validate:"required,max=64"`
+	Pass     string `json:"pass" validate:"required,max=30"`
+	Device   string `json:"device" validate:"omitempty,max=128"`
+}
+
+type AdminPassword struct {
+	Pass    string `json:"pass" validate:"required,max=30"`
+	NewPass string `json:"newpass" validate:"required,min=8,max=30"`
+}
+
+func (Admin) TableName() string {
+	return "admin"
+}
+
+func (AdminSession) TableName() string {
+	return "adminsession"
+}
+
+func (asp *AdminServiceProvider) Create(a *Admin, pass string) error {
+	hashedPass, err := utility.GenerateHash(pass)
+	if err != nil {
+		return err
+	}
+
+	a.Password = string(hashedPass)
+	a.Status = general.AdminActive
+	a.Created = time.Now()
+
+	db := orm.Conn
+
+	return db.Create(a).Error
+}
+
+// Login returns the admin named username, and whether pass is its password.
+func (asp *AdminServiceProvider) Login(username, pass string) (*Admin, bool, error) {
+	var (
+		a Admin
+	)
+
+	db := orm.Conn
+	err := db.Where("username = ?", username).First(&a).Error
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &a, utility.CompareHash([]byte(a.Password), pass), nil
+}
+
+func (asp *AdminServiceProvider) GetByID(id uint64) (*Admin, error) {
+	var (
+		a Admin
+	)
+
+	db := orm.Conn
+	err := db.Where("id = ?", id).First(&a).Error
+
+	return &a, err
+}
+
+// ChangePassword replaces the password of admin id if pass is its current
+// one, and reports whether it was.
+func (asp *AdminServiceProvider) ChangePassword(id uint64, pass, newPass string) (bool, error) {
+	a, err := asp.GetByID(id)
+	if err != nil {
+		return false, err
+	}
+
+	if !utility.CompareHash([]byte(a.Password), pass) {
+		return false, nil
+	}
+
+	hashedPass, err := utility.GenerateHash(newPass)
+	if err != nil {
+		return false, err
+	}
+
+	updater := map[string]interface{}{
+		"password": string(hashedPass),
+		"updated":  time.Now(),
+	}
+
+	db := orm.Conn
+
+	return true, db.Model(a).Where("id = ?", id).Updates(updater).Error
+}
+
+func (asp *AdminServiceProvider) CreateSession(s *AdminSession) error {
+	now := time.Now()
+	s.Status = general.SessionActive
+	s.Created = now
+	s.LastSeen = now
+
+	db := orm.Conn
+
+	return db.Create(s).Error
+}
+
+// ActiveSession returns session id unless it was revoked or has expired, in
+// which case gorm.ErrRecordNotFound is returned.
+func (asp *AdminServiceProvider) ActiveSession(id string) (*AdminSession, error) {
+	var (
+		s AdminSession
+	)
+
+	db := orm.Conn
+	err := db.Where("id = ? AND status = ? AND expires > ?", id, general.SessionActive, time.Now()).First(&s).Error
+
+	return &s, err
+}
+
+// RevokeSessions ends every session of adminID except the one with ID
+// except, and returns the IDs of the sessions it ended.
+func (asp *AdminServiceProvider) RevokeSessions(adminID uint64, except string) ([]string, error) {
+	var (
+		s    AdminSession
+		list []AdminSession
+		ids  []string
+	)
+
+	db := orm.Conn
+	err := db.Where("adminid = ? AND status = ? AND id <> ?", adminID, general.SessionActive, except).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range list {
+		ids = append(ids, l.ID)
+	}
+
+	err = db.Model(&s).Where("adminid = ? AND status = ? AND id <> ?", adminID, general.SessionActive, except).Update("status", general.SessionRevoked).Error
+
+	return ids, err
+}
+
+// RevokeSession ends session id of adminID.
+func (asp *AdminServiceProvider) RevokeSession(adminID uint64, id string) error {
+	var (
+		s AdminSession
+	)
+
+	db := orm.Conn
+
+	return db.Model(&s).Where("id = ? AND adminid = ?", id, adminID).Update("status", general.SessionRevoked).Error
+}
@@ -0,0 +1,68 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"time"
+
+	"ShopApi/orm"
+)
+
+type AuditServiceProvider struct {
+}
+
+var AuditService *AuditServiceProvider = &AuditServiceProvider{}
+
+// Audit results
+const (
+	AuditDenied  = "denied"
+	AuditGranted = "granted"
+)
+
+// AdminAudit records an action of an admin: a request refused for lack of
+// a permission, or a change to roles.
+type AdminAudit struct {
+	ID         uint64    `sql:"auto_increment;primary_key;" gorm:"column:id" json:"id"`
+	AdminID    uint64    `gorm:"column:adminid" json:"adminid"`
+	Action     string    `json:"action"`
+	Permission string    `json:"permission"`
+	Result     string    `json:"result"`
+	Detail     string    `json:"detail"`
+	IP         string    `gorm:"column:ip" json:"ip"`
+	RequestID  string    `gorm:"column:requestid" json:"requestid"`
+	Created    time.Time `json:"created"`
+}
+
+func (AdminAudit) TableName() string {
+	return "adminaudit"
+}
+
+func (asp *AuditServiceProvider) Record(a *AdminAudit) error {
+	a.Created = time.Now()
+
+	db := orm.Conn
+
+	return db.Create(a).Error
+}
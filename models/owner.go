@@ -0,0 +1,52 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"github.com/jinzhu/gorm"
+
+	"ShopApi/orm"
+)
+
+// ownedBy scopes a query to the rows of userID. Every query on the carts,
+// addresses and orders of a customer goes through it, so that the row of
+// another user can't be told from a missing one.
+func ownedBy(userID uint64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("userid = ?", userID)
+	}
+}
+
+// updateOwned applies updater to row id of userID, loading it into model
+// first. It returns gorm.ErrRecordNotFound when userID has no such row.
+func updateOwned(model interface{}, userID, id uint64, updater map[string]interface{}) error {
+	db := orm.Conn
+
+	if err := db.Scopes(ownedBy(userID)).Where("id = ?", id).First(model).Error; err != nil {
+		return err
+	}
+
+	return db.Model(model).Scopes(ownedBy(userID)).Where("id = ?", id).Updates(updater).Error
+}
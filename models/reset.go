@@ -0,0 +1,160 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"ShopApi/orm"
+	"ShopApi/utility"
+)
+
+// ResetTokenTTL is how long a password reset token stays valid.
+const ResetTokenTTL = 10 * time.Minute
+
+// ErrResetTokenInvalid is returned by ResetPassword for an unknown, expired
+// or used token.
+var ErrResetTokenInvalid = errors.New("invalid password reset token")
+
+// PasswordReset is a single use token allowing a user who proved owning
+// the phone of the account to set a new password. Only its hash is kept.
+type PasswordReset struct {
+	ID        uint64    `sql:"auto_increment;primary_key;" gorm:"column:id"`
+	UserID    uint64    `gorm:"column:userid"`
+	TokenHash string    `gorm:"column:tokenhash"`
+	Used      bool      `gorm:"column:used"`
+	Expires   time.Time `gorm:"column:expires"`
+	Created   time.Time `gorm:"column:created"`
+}
+
+func (PasswordReset) TableName() string {
+	return "passwordreset"
+}
+
+// VerifyReset exchanges a code sent for the reset purpose for a reset
+// token.
+type VerifyReset struct {
+	Mobile string `json:"mobile" validate:"required,mobile"`
+	Code   string `json:"code" validate:"required,len=6,number"`
+}
+
+type ResetPassword struct {
+	Token   string  `json:"reset_token" validate:"required,max=64"`
+	NewPass *string `json:"newpass" validate:"required,min=6,max=30"`
+}
+
+// PhoneRegistered reports whether phone is the login of an account.
+func (us *UserServiceProvider) PhoneRegistered(phone string) (bool, error) {
+	var (
+		n int
+	)
+
+	db := orm.Conn
+	err := db.Model(&User{}).Where("name = ?", phone).Count(&n).Error
+
+	return n > 0, err
+}
+
+// StartReset returns a reset token for the account of phone, whose
+// ownership an SMS code has proven. It returns gorm.ErrRecordNotFound when
+// no account uses phone.
+func (us *UserServiceProvider) StartReset(phone string) (string, error) {
+	var (
+		u User
+	)
+
+	db := orm.Conn
+
+	if err := db.Where("name = ?", phone).First(&u).Error; err != nil {
+		return "", err
+	}
+
+	token, hash := utility.NewResetToken()
+	now := time.Now()
+
+	r := PasswordReset{
+		UserID:    u.UserID,
+		TokenHash: hash,
+		Expires:   now.Add(ResetTokenTTL),
+		Created:   now,
+	}
+
+	if err := db.Create(&r).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPassword uses token to set the password of its account to newPass
+// and returns the account. Every reset token of the account dies with it.
+func (us *UserServiceProvider) ResetPassword(token, newPass string) (userID uint64, err error) {
+	var (
+		r PasswordReset
+	)
+
+	hashPass, err := utility.GenerateHash(newPass)
+	if err != nil {
+		return 0, err
+	}
+
+	db := orm.Conn
+
+	err = db.Where("tokenhash = ? AND used = ? AND expires > ?", utility.HashResetToken(token), false, time.Now()).First(&r).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, ErrResetTokenInvalid
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	// a concurrent reset may have used the token since it was read
+	res := tx.Model(&PasswordReset{}).Where("userid = ? AND used = ?", r.UserID, false).Update("used", true)
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, ErrResetTokenInvalid
+	}
+
+	err = tx.Model(&User{}).Where("id = ?", r.UserID).Update("password", string(hashPass)).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return r.UserID, nil
+}
@@ -0,0 +1,370 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"ShopApi/general"
+	"ShopApi/orm"
+)
+
+type RoleServiceProvider struct {
+}
+
+var RoleService *RoleServiceProvider = &RoleServiceProvider{}
+
+// ErrLastSuperAdmin is returned when removing the super admin role from the
+// only admin holding it, which would leave nobody able to manage roles.
+var ErrLastSuperAdmin = errors.New("the last super admin can't lose the role")
+
+// ErrBuiltinRole is returned when changing or deleting the super admin role.
+var ErrBuiltinRole = errors.New("the super admin role can't be changed")
+
+type Role struct {
+	ID          uint64     `sql:"auto_increment;primary_key;" gorm:"column:id" json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Permissions []string   `gorm:"-" json:"permissions"`
+	Created     time.Time  `json:"created"`
+	Updated     *time.Time `json:"updated"`
+}
+
+type RolePermission struct {
+	RoleID     uint64 `gorm:"column:roleid"`
+	Permission string `gorm:"column:permission"`
+}
+
+type AdminRole struct {
+	AdminID uint64    `gorm:"column:adminid"`
+	RoleID  uint64    `gorm:"column:roleid"`
+	Created time.Time `gorm:"column:created"`
+}
+
+type CreateRole struct {
+	Name        string   `json:"name" validate:"required,max=32"`
+	Description string   `json:"description" validate:"max=128"`
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,permission"`
+}
+
+type ChangeRole struct {
+	ID          uint64   `json:"id" validate:"required"`
+	Description string   `json:"description" validate:"max=128"`
+	Permissions []string `json:"permissions" validate:"required,min=1,dive,permission"`
+}
+
+type ConRole struct {
+	ID uint64 `json:"id" validate:"required"`
+}
+
+type AssignRole struct {
+	AdminID uint64 `json:"adminid" validate:"required"`
+	RoleID  uint64 `json:"roleid" validate:"required"`
+}
+
+type ConAdminRoles struct {
+	AdminID uint64 `json:"adminid" validate:"required"`
+}
+
+func (Role) TableName() string {
+	return "role"
+}
+
+func (RolePermission) TableName() string {
+	return "rolepermission"
+}
+
+func (AdminRole) TableName() string {
+	return "adminrole"
+}
+
+// List returns every role with its permissions.
+func (rsp *RoleServiceProvider) List() ([]Role, error) {
+	var (
+		roles []Role
+	)
+
+	db := orm.Conn
+	if err := db.Order("id").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	return roles, rsp.withPermissions(roles)
+}
+
+func (rsp *RoleServiceProvider) GetByID(id uint64) (*Role, error) {
+	var (
+		r Role
+	)
+
+	db := orm.Conn
+	if err := db.Where("id = ?", id).First(&r).Error; err != nil {
+		return nil, err
+	}
+
+	roles := []Role{r}
+
+	return &roles[0], rsp.withPermissions(roles)
+}
+
+// withPermissions fills in the permissions of roles.
+func (rsp *RoleServiceProvider) withPermissions(roles []Role) error {
+	var (
+		ids   []uint64
+		perms []RolePermission
+	)
+
+	if len(roles) == 0 {
+		return nil
+	}
+
+	for _, r := range roles {
+		ids = append(ids, r.ID)
+	}
+
+	db := orm.Conn
+	if err := db.Where("roleid IN (?)", ids).Order("permission").Find(&perms).Error; err != nil {
+		return err
+	}
+
+	for i := range roles {
+		roles[i].Permissions = []string{}
+		for _, p := range perms {
+			if p.RoleID == roles[i].ID {
+				roles[i].Permissions = append(roles[i].Permissions, p.Permission)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (rsp *RoleServiceProvider) Create(r *Role) (err error) {
+	r.Created = time.Now()
+
+	db := orm.Conn
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	if err = tx.Create(r).Error; err != nil {
+		return err
+	}
+
+	return grant(tx, r.ID, r.Permissions)
+}
+
+// Change replaces the description and the permissions of role id.
+func (rsp *RoleServiceProvider) Change(id uint64, description string, perms []string) (err error) {
+	r, err := rsp.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if r.Name == general.RoleSuper {
+		return ErrBuiltinRole
+	}
+
+	updater := map[string]interface{}{
+		"description": description,
+		"updated":     time.Now(),
+	}
+
+	db := orm.Conn
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	if err = tx.Model(r).Where("id = ?", id).Updates(updater).Error; err != nil {
+		return err
+	}
+
+	if err = tx.Where("roleid = ?", id).Delete(RolePermission{}).Error; err != nil {
+		return err
+	}
+
+	return grant(tx, id, perms)
+}
+
+// Delete removes role id and takes it away from the admins holding it.
+func (rsp *RoleServiceProvider) Delete(id uint64) (err error) {
+	r, err := rsp.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	if r.Name == general.RoleSuper {
+		return ErrBuiltinRole
+	}
+
+	db := orm.Conn
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	if err = tx.Where("roleid = ?", id).Delete(AdminRole{}).Error; err != nil {
+		return err
+	}
+
+	if err = tx.Where("roleid = ?", id).Delete(RolePermission{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("id = ?", id).Delete(Role{}).Error
+}
+
+func grant(tx *gorm.DB, roleID uint64, perms []string) error {
+	seen := make(map[string]bool)
+
+	for _, p := range perms {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		if err := tx.Create(&RolePermission{RoleID: roleID, Permission: p}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Assign gives role roleID to admin adminID. Assigning a role twice is not
+// an error.
+func (rsp *RoleServiceProvider) Assign(adminID, roleID uint64) error {
+	var (
+		ar AdminRole
+	)
+
+	db := orm.Conn
+	err := db.Where("adminid = ? AND roleid = ?", adminID, roleID).First(&ar).Error
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	return db.Create(&AdminRole{AdminID: adminID, RoleID: roleID, Created: time.Now()}).Error
+}
+
+func (rsp *RoleServiceProvider) GetByName(name string) (*Role, error) {
+	var (
+		r Role
+	)
+
+	db := orm.Conn
+	err := db.Where("name = ?", name).First(&r).Error
+
+	return &r, err
+}
+
+// AssignByName gives the role called name to admin adminID.
+func (rsp *RoleServiceProvider) AssignByName(adminID uint64, name string) error {
+	r, err := rsp.GetByName(name)
+	if err != nil {
+		return err
+	}
+
+	return rsp.Assign(adminID, r.ID)
+}
+
+// Unassign takes role roleID away from admin adminID.
+func (rsp *RoleServiceProvider) Unassign(adminID, roleID uint64) error {
+	var (
+		holders int
+	)
+
+	r, err := rsp.GetByID(roleID)
+	if err != nil {
+		return err
+	}
+
+	db := orm.Conn
+
+	if r.Name == general.RoleSuper {
+		err = db.Model(&AdminRole{}).Where("roleid = ? AND adminid <> ?", roleID, adminID).Count(&holders).Error
+		if err != nil {
+			return err
+		}
+
+		if holders == 0 {
+			return ErrLastSuperAdmin
+		}
+	}
+
+	return db.Where("adminid = ? AND roleid = ?", adminID, roleID).Delete(AdminRole{}).Error
+}
+
+// AdminRoles returns the roles of admin adminID with their permissions.
+func (rsp *RoleServiceProvider) AdminRoles(adminID uint64) ([]Role, error) {
+	var (
+		roles []Role
+	)
+
+	db := orm.Conn
+	err := db.Select("role.*").Joins("JOIN adminrole ON adminrole.roleid = role.id").
+		Where("adminrole.adminid = ?", adminID).Order("role.id").Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, rsp.withPermissions(roles)
+}
+
+// Permissions returns the permissions granted to admin adminID by all of
+// its roles.
+func (rsp *RoleServiceProvider) Permissions(adminID uint64) ([]string, error) {
+	var (
+		perms []string
+	)
+
+	db := orm.Conn
+	err := db.Table("rolepermission").
+		Joins("JOIN adminrole ON adminrole.roleid = rolepermission.roleid").
+		Where("adminrole.adminid = ?", adminID).
+		Pluck("DISTINCT rolepermission.permission", &perms).Error
+
+	return perms, err
+}
@@ -0,0 +1,201 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"ShopApi/general"
+	"ShopApi/orm"
+)
+
+type SessionServiceProvider struct {
+}
+
+var SessionService *SessionServiceProvider = &SessionServiceProvider{}
+
+// ErrRefreshTokenReused means a refresh token that was already rotated has
+// been presented again.
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// UserSession is one login of a user on one device, either through a
+// session cookie or through access and refresh tokens.
+type UserSession struct {
+	ID          string    `sql:"primary_key" gorm:"column:id" json:"id"`
+	UserID      uint64    `gorm:"column:userid" json:"-"`
+	Kind        string    `json:"kind"`
+	CookieID    string    `gorm:"column:cookieid" json:"-"`
+	RefreshHash string    `gorm:"column:refreshhash" json:"-"`
+	Device      string    `json:"device"`
+	IP          string    `gorm:"column:ip" json:"ip"`
+	Status      uint8     `json:"-"`
+	Created     time.Time `json:"created"`
+	LastSeen    time.Time `gorm:"column:lastseen" json:"lastseen"`
+	Expires     time.Time `json:"expires"`
+	Current     bool      `sql:"-" json:"current"`
+}
+
+func (UserSession) TableName() string {
+	return "usersession"
+}
+
+func (ssp *SessionServiceProvider) Create(s *UserSession) error {
+	now := time.Now()
+	s.Status = general.SessionActive
+	s.Created = now
+	s.LastSeen = now
+
+	db := orm.Conn
+
+	return db.Create(s).Error
+}
+
+// Active returns session id of userID unless it was revoked or has expired,
+// in which case gorm.ErrRecordNotFound is returned.
+func (ssp *SessionServiceProvider) Active(id string, userID uint64) (*UserSession, error) {
+	var (
+		s UserSession
+	)
+
+	db := orm.Conn
+	err := db.Where("id = ? AND userid = ? AND status = ? AND expires > ?", id, userID, general.SessionActive, time.Now()).First(&s).Error
+
+	return &s, err
+}
+
+// Rotate replaces the refresh token hash of session id. Presenting a hash
+// that is no longer current revokes the session and returns
+// ErrRefreshTokenReused.
+func (ssp *SessionServiceProvider) Rotate(id, hash, newHash, ip string, expires time.Time) (*UserSession, error) {
+	var (
+		s   UserSession
+		err error
+	)
+
+	updater := map[string]interface{}{
+		"refreshhash": newHash,
+		"ip":          ip,
+		"lastseen":    time.Now(),
+		"expires":     expires,
+	}
+
+	db := orm.Conn
+	result := db.Model(&s).Where("id = ? AND refreshhash = ? AND status = ? AND expires > ?", id, hash, general.SessionActive, time.Now()).Updates(updater)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	err = db.Where("id = ?", id).First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if result.RowsAffected == 1 {
+		return &s, nil
+	}
+
+	if s.Status == general.SessionActive && s.RefreshHash != hash {
+		err = db.Model(&s).Where("id = ?", id).Update("status", general.SessionRevoked).Error
+		if err != nil {
+			return nil, err
+		}
+
+		return &s, ErrRefreshTokenReused
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Touch records activity on session id, and pushes its expiry back by
+// extend if extend is positive.
+func (ssp *SessionServiceProvider) Touch(id string, extend time.Duration) error {
+	var (
+		s UserSession
+	)
+
+	now := time.Now()
+	updater := map[string]interface{}{"lastseen": now}
+	if extend > 0 {
+		updater["expires"] = now.Add(extend)
+	}
+
+	db := orm.Conn
+
+	return db.Model(&s).Where("id = ? AND status = ?", id, general.SessionActive).Updates(updater).Error
+}
+
+func (ssp *SessionServiceProvider) ListActive(userID uint64) ([]UserSession, error) {
+	var (
+		list []UserSession
+	)
+
+	db := orm.Conn
+	err := db.Where("userid = ? AND status = ? AND expires > ?", userID, general.SessionActive, time.Now()).Order("lastseen desc").Find(&list).Error
+
+	return list, err
+}
+
+// Revoke ends session id of userID. Sessions of other users are reported as
+// gorm.ErrRecordNotFound.
+func (ssp *SessionServiceProvider) Revoke(userID uint64, id string) (*UserSession, error) {
+	var (
+		s UserSession
+	)
+
+	db := orm.Conn
+	err := db.Where("id = ? AND userid = ? AND status = ?", id, userID, general.SessionActive).First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(&s).Where("id = ?", id).Update("status", general.SessionRevoked).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// RevokeAll ends every session of userID except the one with ID except, and
+// returns the sessions it ended.
+func (ssp *SessionServiceProvider) RevokeAll(userID uint64, except string) ([]UserSession, error) {
+	var (
+		s    UserSession
+		list []UserSession
+	)
+
+	db := orm.Conn
+	err := db.Where("userid = ? AND status = ? AND id <> ?", userID, general.SessionActive, except).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(&s).Where("userid = ? AND status = ? AND id <> ?", userID, general.SessionActive, except).Update("status", general.SessionRevoked).Error
+
+	return list, err
+}
@@ -0,0 +1,109 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"ShopApi/orm"
+	"ShopApi/utility"
+)
+
+func init() {
+	utility.RegisterSessionStore("mysql", func(utility.SessionConfig) (utility.SessionStore, error) {
+		return &SessionStoreProvider{}, nil
+	})
+}
+
+// SessionStoreProvider keeps cookie sessions in MySQL so that they survive
+// restarts and are shared by every instance.
+type SessionStoreProvider struct {
+}
+
+type SessionData struct {
+	ID      string    `sql:"primary_key" gorm:"column:id"`
+	Data    []byte    `gorm:"column:data"`
+	Expires time.Time `gorm:"column:expires"`
+}
+
+func (SessionData) TableName() string {
+	return "sessiondata"
+}
+
+func (ssp *SessionStoreProvider) Load(sid string) ([]byte, time.Time, error) {
+	var (
+		s SessionData
+	)
+
+	db := orm.Conn
+	err := db.Where("id = ?", sid).First(&s).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, time.Time{}, utility.ErrSessionNotFound
+	}
+
+	return s.Data, s.Expires, err
+}
+
+func (ssp *SessionStoreProvider) Save(sid string, data []byte, expires time.Time) error {
+	db := orm.Conn
+
+	return db.Exec("INSERT INTO sessiondata (id, data, expires) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE data = VALUES(data), expires = VALUES(expires)", sid, data, expires).Error
+}
+
+func (ssp *SessionStoreProvider) Delete(sid string) error {
+	db := orm.Conn
+
+	return db.Where("id = ?", sid).Delete(&SessionData{}).Error
+}
+
+func (ssp *SessionStoreProvider) GC() error {
+	db := orm.Conn
+
+	return db.Where("expires <= ?", time.Now()).Delete(&SessionData{}).Error
+}
+
+func (ssp *SessionStoreProvider) Ping(ctx context.Context) error {
+	var (
+		one int
+	)
+
+	db := orm.Conn
+
+	return db.DB().QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+func (ssp *SessionStoreProvider) Count() (int, error) {
+	var (
+		count int
+	)
+
+	db := orm.Conn
+	err := db.Model(&SessionData{}).Where("expires > ?", time.Now()).Count(&count).Error
+
+	return count, err
+}
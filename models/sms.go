@@ -0,0 +1,221 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"ShopApi/orm"
+	"ShopApi/sms"
+)
+
+type SMSServiceProvider struct {
+}
+
+var SMSService *SMSServiceProvider = &SMSServiceProvider{}
+
+var (
+	// ErrSMSTooFrequent is returned by Issue when the phone or the client
+	// IP asked for too many codes.
+	ErrSMSTooFrequent = errors.New("verification codes requested too often")
+
+	// ErrSMSCodeInvalid is returned by Verify for a wrong, expired, used or
+	// exhausted code.
+	ErrSMSCodeInvalid = errors.New("invalid verification code")
+)
+
+// SMSCode is a verification code sent to a phone. Only the latest code of a
+// phone and purpose verifies; it is single use and dies after
+// sms.Limits.Attempts wrong guesses.
+type SMSCode struct {
+	ID       uint64    `sql:"auto_increment;primary_key;" gorm:"column:id"`
+	Phone    string    `gorm:"column:phone"`
+	Purpose  string    `gorm:"column:purpose"`
+	Code     string    `gorm:"column:code"`
+	IP       string    `gorm:"column:ip"`
+	Attempts int       `gorm:"column:attempts"`
+	Used     bool      `gorm:"column:used"`
+	Expires  time.Time `gorm:"column:expires"`
+	Created  time.Time `gorm:"column:created"`
+}
+
+func (SMSCode) TableName() string {
+	return "smscode"
+}
+
+type SendSMS struct {
+	Phone   string `json:"phone" validate:"required,mobile"`
+	Purpose string `json:"purpose" validate:"required,smspurpose"`
+}
+
+// SMSLogin logs in with a code sent for the login purpose.
+type SMSLogin struct {
+	Mobile string `json:"mobile" validate:"required,mobile"`
+	Code   string `json:"code" validate:"required,len=6,number"`
+	Token  bool   `json:"token"`
+	Device string `json:"device" validate:"omitempty,max=128"`
+}
+
+// Message returns the SMS delivering the code.
+func (s *SMSCode) Message() *sms.Message {
+	return &sms.Message{
+		Phone:   s.Phone,
+		Purpose: s.Purpose,
+		Code:    s.Code,
+		TTL:     s.Expires.Sub(s.Created),
+	}
+}
+
+// Issue creates a code for phone and purpose requested from ip, replacing
+// the earlier ones. It returns ErrSMSTooFrequent when the phone got a code
+// less than an interval ago or reached its daily bound, or when ip reached
+// its hourly bound. The bounds are checked in the transaction creating the
+// code, which locks the latest code of phone, so that concurrent requests
+// for one phone can't all pass them.
+func (ssp *SMSServiceProvider) Issue(phone, purpose, ip string) (s *SMSCode, err error) {
+	var (
+		last SMSCode
+		n    int
+	)
+
+	limits := sms.CurrentLimits()
+	now := time.Now()
+
+	code, err := sms.NewCode()
+	if err != nil {
+		return nil, err
+	}
+
+	db := orm.Conn
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	err = tx.Raw("SELECT * FROM smscode WHERE phone = ? ORDER BY id DESC LIMIT 1 FOR UPDATE", phone).Scan(&last).Error
+	if err == nil && now.Sub(last.Created) < limits.Interval {
+		return nil, ErrSMSTooFrequent
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if limits.PerPhone > 0 {
+		if err = tx.Model(&SMSCode{}).Where("phone = ? AND created > ?", phone, now.Add(-24*time.Hour)).Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n >= limits.PerPhone {
+			return nil, ErrSMSTooFrequent
+		}
+	}
+
+	if limits.PerIP > 0 {
+		if err = tx.Model(&SMSCode{}).Where("ip = ? AND created > ?", ip, now.Add(-time.Hour)).Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n >= limits.PerIP {
+			return nil, ErrSMSTooFrequent
+		}
+	}
+
+	s = &SMSCode{
+		Phone:   phone,
+		Purpose: purpose,
+		Code:    code,
+		IP:      ip,
+		Expires: now.Add(limits.CodeTTL),
+		Created: now,
+	}
+
+	err = tx.Model(&SMSCode{}).Where("phone = ? AND purpose = ? AND used = ?", phone, purpose, false).Update("used", true).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Create(s).Error; err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Verify consumes the code of phone for purpose. Every guess reserves one of
+// the allowed attempts before comparing, so that concurrent guesses can't
+// exceed them; the last allowed one invalidates the code.
+func (ssp *SMSServiceProvider) Verify(phone, purpose, code string) error {
+	var (
+		s SMSCode
+	)
+
+	limits := sms.CurrentLimits()
+
+	db := orm.Conn
+
+	err := db.Where("phone = ? AND purpose = ? AND used = ? AND expires > ?", phone, purpose, false, time.Now()).Order("id DESC").First(&s).Error
+	if err == gorm.ErrRecordNotFound {
+		return ErrSMSCodeInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	res := db.Model(&SMSCode{}).Where("id = ? AND attempts < ? AND used = ?", s.ID, limits.Attempts, false).Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSMSCodeInvalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(s.Code), []byte(code)) != 1 {
+		if s.Attempts+1 >= limits.Attempts {
+			if err = db.Model(&SMSCode{}).Where("id = ?", s.ID).Update("used", true).Error; err != nil {
+				return err
+			}
+		}
+
+		return ErrSMSCodeInvalid
+	}
+
+	// a concurrent Verify may have used the code since it was read
+	res = db.Model(&SMSCode{}).Where("id = ? AND used = ?", s.ID, false).Update("used", true)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSMSCodeInvalid
+	}
+
+	return nil
+}
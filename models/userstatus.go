@@ -0,0 +1,157 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"errors"
+	"time"
+
+	"ShopApi/general"
+	"ShopApi/orm"
+)
+
+// ErrUserSuspended is returned by Login for a suspended account, once the
+// password has been checked.
+var ErrUserSuspended = errors.New("the account is suspended")
+
+// UserStatusHistory records a change of the status of a user. AdminID is 0
+// when a suspension lifted itself on expiry.
+type UserStatusHistory struct {
+	ID         uint64     `sql:"auto_increment;primary_key;" gorm:"column:id" json:"id"`
+	UserID     uint64     `gorm:"column:userid" json:"userid"`
+	FromStatus uint16     `gorm:"column:fromstatus" json:"fromstatus"`
+	ToStatus   uint16     `gorm:"column:tostatus" json:"tostatus"`
+	Reason     string     `json:"reason"`
+	Until      *time.Time `json:"until"`
+	AdminID    uint64     `gorm:"column:adminid" json:"adminid"`
+	Created    time.Time  `json:"created"`
+}
+
+type SuspendUser struct {
+	UserID uint64     `json:"userid" validate:"required"`
+	Reason string     `json:"reason" validate:"required,max=255"`
+	Until  *time.Time `json:"until"`
+}
+
+type ReactivateUser struct {
+	UserID uint64 `json:"userid" validate:"required"`
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+type ConUserHistory struct {
+	UserID uint64 `json:"userid" validate:"required"`
+}
+
+func (UserStatusHistory) TableName() string {
+	return "userstatushistory"
+}
+
+// checkActive returns ErrUserSuspended unless u is active. A suspension
+// whose expiry has passed is lifted first.
+func (us *UserServiceProvider) checkActive(u *User) error {
+	if u.Status != general.UserInactive {
+		return nil
+	}
+
+	if u.SuspendedUntil == nil || time.Now().Before(*u.SuspendedUntil) {
+		return ErrUserSuspended
+	}
+
+	return us.SetStatus(u.UserID, general.UserActive, "suspension expired", nil, 0)
+}
+
+// CheckActive returns ErrUserSuspended unless userID is active, and
+// gorm.ErrRecordNotFound if there is no such user.
+func (us *UserServiceProvider) CheckActive(userID uint64) error {
+	var (
+		u User
+	)
+
+	db := orm.Conn
+	if err := db.Where("id = ?", userID).First(&u).Error; err != nil {
+		return err
+	}
+
+	return us.checkActive(&u)
+}
+
+// SetStatus changes the status of userID and records the change. until is
+// when a suspension lifts itself, nil for never. adminID is who made the
+// change, 0 for the service itself.
+func (us *UserServiceProvider) SetStatus(userID uint64, status uint16, reason string, until *time.Time, adminID uint64) (err error) {
+	var (
+		u User
+	)
+
+	db := orm.Conn
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	if err = tx.Where("id = ?", userID).First(&u).Error; err != nil {
+		return err
+	}
+
+	from := u.Status
+
+	updater := map[string]interface{}{
+		"status":         status,
+		"suspendeduntil": until,
+	}
+
+	if err = tx.Model(&u).Updates(updater).Error; err != nil {
+		return err
+	}
+
+	history := &UserStatusHistory{
+		UserID:     userID,
+		FromStatus: from,
+		ToStatus:   status,
+		Reason:     reason,
+		Until:      until,
+		AdminID:    adminID,
+		Created:    time.Now(),
+	}
+
+	return tx.Create(history).Error
+}
+
+// StatusHistory returns the status changes of userID, newest first.
+func (us *UserServiceProvider) StatusHistory(userID uint64) ([]UserStatusHistory, error) {
+	var (
+		list []UserStatusHistory
+	)
+
+	db := orm.Conn
+	err := db.Where("userid = ?", userID).Order("created DESC, id DESC").Find(&list).Error
+
+	return list, err
+}
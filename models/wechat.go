@@ -0,0 +1,293 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+
+	"ShopApi/general"
+	"ShopApi/orm"
+)
+
+var (
+	// ErrWechatBound is returned when binding a WeChat identity that
+	// another account already uses, or binding a second identity.
+	ErrWechatBound = errors.New("the WeChat identity or the account is already bound")
+
+	// ErrWechatUnbind is returned when unbinding the identity of an account
+	// created by WeChat login, which has no other way to log in.
+	ErrWechatUnbind = errors.New("an account created with WeChat can't unbind it")
+
+	// ErrNothingToMerge is returned by MergeWechat when no other account
+	// uses the WeChat identity.
+	ErrNothingToMerge = errors.New("no other account uses the WeChat identity")
+)
+
+type WechatLogin struct {
+	Code   string `json:"code" validate:"required,max=128"`
+	Token  bool   `json:"token"`
+	Device string `json:"device" validate:"omitempty,max=128"`
+}
+
+type WechatCode struct {
+	Code string `json:"code" validate:"required,max=128"`
+}
+
+// errDuplicateKey is the MySQL error of a write breaking a unique key.
+const errDuplicateKey = 1062
+
+// isDuplicateKey reports whether err is a write breaking a unique key, such
+// as binding an openid that another account took meanwhile.
+func isDuplicateKey(err error) bool {
+	e, ok := err.(*mysql.MySQLError)
+
+	return ok && e.Number == errDuplicateKey
+}
+
+// mergedTables hold the rows moved to the surviving account of a merge.
+var mergedTables = []string{"carts", "contact", "orders"}
+
+// GetByOpenID returns the account bound to the WeChat identity openID.
+func (us *UserServiceProvider) GetByOpenID(openID string) (*User, error) {
+	var (
+		u User
+	)
+
+	if openID == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	db := orm.Conn
+	err := db.Where("openid = ?", openID).First(&u).Error
+
+	return &u, err
+}
+
+// WechatLogin returns the account bound to openID, creating a WechatUser
+// account on first login. It returns ErrUserSuspended for a suspended one.
+func (us *UserServiceProvider) WechatLogin(openID, nickname, avatar string) (*User, bool, error) {
+	u, err := us.GetByOpenID(openID)
+	if err == nil {
+		return u, false, us.checkActive(u)
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, false, err
+	}
+
+	u, err = us.createWechatUser(openID, nickname, avatar)
+	if err == ErrWechatBound {
+		// a concurrent first login created the account
+		u, err = us.GetByOpenID(openID)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return u, false, us.checkActive(u)
+	}
+
+	return u, err == nil, err
+}
+
+func (us *UserServiceProvider) createWechatUser(openID, nickname, avatar string) (u *User, err error) {
+	u = &User{
+		OpenID:  openID,
+		Status:  general.UserActive,
+		Type:    general.WechatUser,
+		Created: time.Now(),
+	}
+
+	db := orm.Conn
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	// name is the phone number of phone users and unique; WeChat users
+	// have none.
+	if err = tx.Omit("name").Create(u).Error; err != nil {
+		if isDuplicateKey(err) {
+			err = ErrWechatBound
+		}
+
+		return nil, err
+	}
+
+	info := UserInfo{
+		UserID:   u.UserID,
+		Nickname: nickname,
+		Avatar:   avatar,
+		Sex:      general.Man,
+	}
+
+	if err = tx.Create(&info).Error; err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// BindWechat binds openID to account userID. It returns ErrWechatBound when
+// openID belongs to another account or userID is bound to another identity.
+func (us *UserServiceProvider) BindWechat(userID uint64, openID string) error {
+	var (
+		u User
+	)
+
+	other, err := us.GetByOpenID(openID)
+	if err == nil {
+		if other.UserID == userID {
+			return nil
+		}
+
+		return ErrWechatBound
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	db := orm.Conn
+	if err = db.Where("id = ?", userID).First(&u).Error; err != nil {
+		return err
+	}
+
+	if u.OpenID != "" {
+		return ErrWechatBound
+	}
+
+	err = db.Model(&u).Update("openid", openID).Error
+	if isDuplicateKey(err) {
+		return ErrWechatBound
+	}
+
+	return err
+}
+
+// UnbindWechat removes the WeChat identity of userID. Accounts created by
+// WeChat login can't unbind it.
+func (us *UserServiceProvider) UnbindWechat(userID uint64) error {
+	var (
+		u User
+	)
+
+	db := orm.Conn
+	if err := db.Where("id = ?", userID).First(&u).Error; err != nil {
+		return err
+	}
+
+	if u.Type == general.WechatUser {
+		return ErrWechatUnbind
+	}
+
+	// openid is unique and NULL when unbound
+	return db.Model(&u).Update("openid", gorm.Expr("NULL")).Error
+}
+
+// MergeWechat folds the WechatUser account bound to openID into account
+// userID: its carts, addresses and orders move to userID, which takes over
+// the WeChat identity, and it is deactivated. It returns the ID of the
+// merged account.
+func (us *UserServiceProvider) MergeWechat(userID uint64, openID string) (mergedID uint64, err error) {
+	var (
+		u User
+	)
+
+	other, err := us.GetByOpenID(openID)
+	if err == gorm.ErrRecordNotFound || (err == nil && other.UserID == userID) {
+		return 0, ErrNothingToMerge
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Only an account created by WeChat login is merged away: a phone
+	// account bound to openID has its own credentials.
+	if other.Type != general.WechatUser {
+		return 0, ErrWechatBound
+	}
+
+	db := orm.Conn
+	if err = db.Where("id = ?", userID).First(&u).Error; err != nil {
+		return 0, err
+	}
+
+	if u.OpenID != "" {
+		return 0, ErrWechatBound
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit().Error
+		}
+	}()
+
+	for _, table := range mergedTables {
+		err = tx.Table(table).Where("userid = ?", other.UserID).Update("userid", userID).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	from := other.Status
+
+	err = tx.Model(other).Updates(map[string]interface{}{"openid": gorm.Expr("NULL"), "status": general.UserInactive}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Model(&u).Update("openid", openID).Error; err != nil {
+		if isDuplicateKey(err) {
+			err = ErrWechatBound
+		}
+
+		return 0, err
+	}
+
+	history := &UserStatusHistory{
+		UserID:     other.UserID,
+		FromStatus: from,
+		ToStatus:   general.UserInactive,
+		Reason:     fmt.Sprintf("merged into user %d", userID),
+		Created:    time.Now(),
+	}
+
+	if err = tx.Create(history).Error; err != nil {
+		return 0, err
+	}
+
+	return other.UserID, nil
+}
@@ -0,0 +1,250 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"ShopApi/general"
+)
+
+// Route describes one route of the API. Request and Response are values of
+// the request body bound by the handler and of the data it answers with;
+// either may be nil. Raw routes answer with Response itself instead of the
+// envelope, as ContentType when it is not JSON.
+type Route struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Auth        bool
+	Admin       bool
+	Permission  string
+	List        bool
+	Raw         bool
+	Deprecated  bool
+	ContentType string
+	Request     interface{}
+	Response    interface{}
+}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower-case HTTP methods of a path to their operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+const jsonContent = "application/json"
+
+// New documents routes. Login is required on Auth routes through either a
+// bearer token or the session cookie named cookie, and on Admin routes
+// through an admin access token whose roles grant Permission.
+func New(title, version, cookie string, routes []Route) *Document {
+	g := newGenerator()
+
+	d := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]PathItem),
+	}
+
+	envelope := g.schemaOf(reflect.TypeOf(general.Envelope{}))
+	meta := g.schemaOf(reflect.TypeOf(general.Meta{}))
+
+	for _, r := range routes {
+		op := &Operation{
+			Tags:        []string{r.Tag},
+			Summary:     r.Summary,
+			OperationID: operationID(r.Method, r.Path),
+			Deprecated:  r.Deprecated,
+			Responses: map[string]*Response{
+				"200":     {Description: "OK", Content: g.content(r, envelope, meta)},
+				"default": {Description: "Error", Content: map[string]MediaType{jsonContent: {Schema: envelope}}},
+			},
+		}
+
+		if r.Raw {
+			delete(op.Responses, "default")
+		}
+
+		if r.Auth {
+			op.Security = []map[string][]string{{"bearerAuth": {}}, {"sessionCookie": {}}}
+			op.Responses["401"] = &Response{Description: "Login required", Content: map[string]MediaType{jsonContent: {Schema: envelope}}}
+		}
+
+		if r.Admin {
+			op.Security = []map[string][]string{{"adminAuth": {}}}
+			op.Responses["401"] = &Response{Description: "Admin login required", Content: map[string]MediaType{jsonContent: {Schema: envelope}}}
+			op.Responses["403"] = &Response{Description: "Admin account disabled", Content: map[string]MediaType{jsonContent: {Schema: envelope}}}
+		}
+
+		if r.Permission != "" {
+			op.Description = "Requires the " + r.Permission + " permission."
+			op.Responses["403"] = &Response{Description: "Admin account disabled or permission denied", Content: map[string]MediaType{jsonContent: {Schema: envelope}}}
+		}
+
+		if r.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{jsonContent: {Schema: g.schemaOf(reflect.TypeOf(r.Request))}},
+			}
+			op.Responses["400"] = &Response{Description: "Invalid parameters", Content: map[string]MediaType{jsonContent: {Schema: envelope}}}
+		}
+
+		item, ok := d.Paths[r.Path]
+		if !ok {
+			item = make(PathItem)
+			d.Paths[r.Path] = item
+		}
+		item[strings.ToLower(r.Method)] = op
+	}
+
+	d.Components = Components{
+		Schemas: g.schemas,
+		SecuritySchemes: map[string]*SecurityScheme{
+			"bearerAuth":    {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			"sessionCookie": {Type: "apiKey", In: "cookie", Name: cookie},
+			"adminAuth":     {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "Access token from /admin/api/v1/login"},
+		},
+	}
+
+	return d
+}
+
+// content returns the body of a successful answer of r.
+func (g *generator) content(r Route, envelope, meta *Schema) map[string]MediaType {
+	if r.Raw {
+		contentType := r.ContentType
+		if contentType == "" {
+			contentType = jsonContent
+		}
+
+		var schema *Schema
+		if r.Response != nil {
+			schema = g.schemaOf(reflect.TypeOf(r.Response))
+		}
+
+		return map[string]MediaType{contentType: {Schema: schema}}
+	}
+
+	data := &Schema{Nullable: true}
+	if r.Response != nil {
+		data = g.schemaOf(reflect.TypeOf(r.Response))
+	}
+
+	props := map[string]*Schema{"data": data}
+	if r.List {
+		props["meta"] = meta
+	}
+
+	return map[string]MediaType{jsonContent: {Schema: &Schema{
+		AllOf: []*Schema{envelope, {Type: "object", Properties: props}},
+	}}}
+}
+
+// Has reports whether d documents method on path.
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[path][strings.ToLower(method)]
+
+	return ok
+}
+
+// Operations returns the "METHOD path" of every documented operation, sorted.
+func (d *Document) Operations() []string {
+	var ops []string
+
+	for path, item := range d.Paths {
+		for method := range item {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+
+	return ops
+}
+
+// operationID turns "POST /api/v1/user/login" into "post_api_v1_user_login".
+func operationID(method, path string) string {
+	id := strings.ToLower(method) + strings.Map(func(r rune) rune {
+		if r == '/' || r == '.' || r == '-' || r == ':' {
+			return '_'
+		}
+
+		return r
+	}, path)
+
+	return id
+}
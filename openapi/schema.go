@@ -0,0 +1,300 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object built from Go
+// types and their validate tags.
+type Schema struct {
+	Ref              string             `json:"$ref,omitempty"`
+	Type             string             `json:"type,omitempty"`
+	Format           string             `json:"format,omitempty"`
+	Nullable         bool               `json:"nullable,omitempty"`
+	Properties       map[string]*Schema `json:"properties,omitempty"`
+	Required         []string           `json:"required,omitempty"`
+	Items            *Schema            `json:"items,omitempty"`
+	AllOf            []*Schema          `json:"allOf,omitempty"`
+	MinLength        *float64           `json:"minLength,omitempty"`
+	MaxLength        *float64           `json:"maxLength,omitempty"`
+	MinItems         *float64           `json:"minItems,omitempty"`
+	MaxItems         *float64           `json:"maxItems,omitempty"`
+	Minimum          *float64           `json:"minimum,omitempty"`
+	Maximum          *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum bool               `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       float64            `json:"multipleOf,omitempty"`
+	Pattern          string             `json:"pattern,omitempty"`
+	Enum             []string           `json:"enum,omitempty"`
+	Description      string             `json:"description,omitempty"`
+}
+
+var (
+	tagsMu sync.RWMutex
+	tags   = map[string]func(*Schema){
+		"email":  func(s *Schema) { s.Format = "email" },
+		"url":    func(s *Schema) { s.Format = "uri" },
+		"number": func(s *Schema) { s.Pattern = "^[0-9]+$" },
+	}
+
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+// RegisterTag describes a custom validate tag: fn adds its constraint to
+// the schema of a field carrying it.
+func RegisterTag(tag string, fn func(*Schema)) {
+	tagsMu.Lock()
+	defer tagsMu.Unlock()
+
+	tags[tag] = fn
+}
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema of t. Named structs are added to the
+// components and referenced.
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer", Format: intFormat(t)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: intFormat(t), Minimum: number(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		return g.object(t)
+	}
+
+	// interface{} and anything else carries arbitrary JSON.
+	return &Schema{}
+}
+
+// component registers the named struct t and returns its component name.
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + name
+	}
+
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t)
+
+	return name
+}
+
+func (g *generator) object(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.fields(t, s)
+
+	return s
+}
+
+// fields adds the JSON fields of struct t to s, flattening embedded
+// structs the way encoding/json does.
+func (g *generator) fields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, skip := jsonName(f)
+		if skip {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && ft.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+			g.fields(ft, s)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		field := g.schemaOf(f.Type)
+		if constrain(field, ft, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = field
+	}
+}
+
+// constrain adds the constraints of a validate tag to the schema of a field
+// of type t and reports whether the tag makes the field required.
+func constrain(s *Schema, t reflect.Type, tag string) bool {
+	var required bool
+
+	if tag == "" || tag == "-" {
+		return false
+	}
+
+	str := t.Kind() == reflect.String
+	list := t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		key, value := rule, ""
+		if j := strings.Index(rule, "="); j >= 0 {
+			key, value = rule[:j], rule[j+1:]
+		}
+
+		n, _ := strconv.ParseFloat(value, 64)
+
+		switch key {
+		case "dive":
+			// The remaining rules apply to the items.
+			if s.Items != nil {
+				constrain(s.Items, t.Elem(), strings.Join(rules[i+1:], ","))
+			}
+
+			return required
+		case "required":
+			required = true
+		case "len":
+			if str {
+				s.MinLength, s.MaxLength = number(n), number(n)
+			} else if list {
+				s.MinItems, s.MaxItems = number(n), number(n)
+			} else {
+				s.Minimum, s.Maximum = number(n), number(n)
+			}
+		case "min", "gte":
+			if str {
+				s.MinLength = number(n)
+			} else if list {
+				s.MinItems = number(n)
+			} else {
+				s.Minimum = number(n)
+			}
+		case "max", "lte":
+			if str {
+				s.MaxLength = number(n)
+			} else if list {
+				s.MaxItems = number(n)
+			} else {
+				s.Maximum = number(n)
+			}
+		case "gt":
+			if !str && !list {
+				s.Minimum, s.ExclusiveMinimum = number(n), true
+			}
+		case "lt":
+			if !str && !list {
+				s.Maximum, s.ExclusiveMaximum = number(n), true
+			}
+		default:
+			tagsMu.RLock()
+			fn, ok := tags[key]
+			tagsMu.RUnlock()
+
+			if ok {
+				fn(s)
+			}
+		}
+	}
+
+	return required
+}
+
+// jsonName returns the name a field is encoded with and whether
+// encoding/json skips it.
+func jsonName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+
+	name := tag
+	if i := strings.Index(tag, ","); i >= 0 {
+		name = tag[:i]
+	}
+
+	if name == "" {
+		name = f.Name
+	}
+
+	return name, false
+}
+
+func intFormat(t reflect.Type) string {
+	if t.Bits() == 64 {
+		return "int64"
+	}
+
+	return "int32"
+}
+
+func number(n float64) *float64 {
+	return &n
+}
@@ -0,0 +1,53 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// JSON serves d. The document is encoded once, when the route is set up.
+func JSON(d *Document) echo.HandlerFunc {
+	body, err := json.Marshal(d)
+	if err != nil {
+		panic("[openapi] couldn't encode the document: " + err.Error())
+	}
+
+	return func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, body)
+	}
+}
+
+// Page serves the docs page, which reads openapi.json next to it.
+func Page(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, docsPage)
+}
@@ -0,0 +1,156 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/handler"
+	"ShopApi/log"
+	"ShopApi/orm"
+	"ShopApi/sms"
+	"ShopApi/utility"
+	"ShopApi/wechat"
+)
+
+// App owns the echo server and the resources it depends on, and ties their
+// lifecycle together.
+type App struct {
+	conf   *shopServerConfig
+	server *echo.Echo
+	http   *http.Server
+}
+
+func NewApp(conf *shopServerConfig) *App {
+	subscribeConfiguration(applyReloadable)
+	publishConfiguration(conf)
+	utility.InitToken(conf.tokenKey, conf.tokenTTL, conf.refreshTTL, conf.adminTTL)
+	wechat.Use(conf.wechatLogin())
+
+	// validate has already refused a provider that can't be built
+	sender, _ := conf.smsSender()
+	sms.Use(sender)
+
+	return &App{
+		conf:   conf,
+		server: newServer(),
+		http:   &http.Server{Addr: conf.address},
+	}
+}
+
+// Start connects to MySQL, opens the session store, starts the session GC
+// and serves requests until Shutdown is called. It returns nil after a clean
+// shutdown.
+func (a *App) Start() error {
+	initMysql(a.conf)
+
+	if err := utility.InitSessions(a.conf.sessionConfig()); err != nil {
+		return err
+	}
+	utility.StartSessionGC()
+	watchConfiguration(a.conf)
+
+	err := a.server.StartServer(a.http)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown fails readiness and waits for the drain delay so that load
+// balancers stop routing to the server, stops accepting new connections,
+// waits for in-flight requests until ctx is done, then releases the session
+// GC and the database.
+func (a *App) Shutdown(ctx context.Context) error {
+	handler.SetDraining(true)
+
+	select {
+	case <-time.After(a.conf.drainDelay):
+	case <-ctx.Done():
+	}
+
+	return a.stop(ctx)
+}
+
+// stop is Shutdown without the drain delay.
+func (a *App) stop(ctx context.Context) error {
+	err := a.http.Shutdown(ctx)
+	if err != nil {
+		log.Logger.Error("Drain requests with error:", err)
+	}
+
+	utility.StopSessionGC()
+
+	if e := orm.CloseOrm(); e != nil {
+		log.Logger.Error("Close mysql with error:", e)
+
+		if err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// Run starts the App and shuts it down gracefully on SIGINT or SIGTERM.
+func (a *App) Run() error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- a.Start()
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-errs:
+		// a server that failed to start has no traffic to drain
+		handler.SetDraining(true)
+		a.stop(context.Background())
+
+		return err
+	case sig := <-quit:
+		log.Logger.Info("Received %s, shutting down", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.conf.shutdown)
+	defer cancel()
+
+	err := a.Shutdown(ctx)
+	if e := <-errs; e != nil && err == nil {
+		err = e
+	}
+
+	return err
+}
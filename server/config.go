@@ -1,73 +1,667 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/18        Yusan Kurban
- */
-
-package main
-
-import (
-	"github.com/spf13/viper"
-)
-
-type shopServerConfig struct {
-	address   string
-	isDebug   bool
-	corsHosts []string
-	tokenKey  string
-	mysqlHost string
-	mysqlPort string
-	mysqlUser string
-	mysqlPass string
-	mysqlDb   string
-	mysqlSize int
-}
-
-var (
-	configuration *shopServerConfig
-)
-
-func readConfiguration() {
-	viper.AddConfigPath("./")
-	viper.SetConfigName("config")
-
-	if err := viper.ReadInConfig(); err != nil {
-		panic(err)
-	}
-
-	configuration = &shopServerConfig{
-		address:   viper.GetString("server.address"),
-		isDebug:   viper.GetBool("server.debug"),
-		corsHosts: viper.GetStringSlice("middleware.cors.hosts"),
-		tokenKey:  viper.GetString("middleware.jwt.tokenkey"),
-		mysqlHost: viper.GetString("mysql.host"),
-		mysqlPort: viper.GetString("mysql.port"),
-		mysqlUser: viper.GetString("mysql.user"),
-		mysqlPass: viper.GetString("mysql.pass"),
-		mysqlDb:   viper.GetString("mysql.db"),
-		mysqlSize: viper.GetInt("mysql.size"),
-	}
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/18        Yusan Kurban
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+
+	"ShopApi/handler"
+	"ShopApi/log"
+	"ShopApi/sms"
+	"ShopApi/utility"
+	"ShopApi/wechat"
+)
+
+const (
+	envPrefix      = "shopapi"
+	defaultProfile = "dev"
+	redacted       = "******"
+)
+
+var profiles = []string{"dev", "test", "prod"}
+
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// shopServerConfig is an immutable snapshot of the configuration. Fields
+// marked as reloadable may change while the server is running, the others
+// take effect after a restart.
+type shopServerConfig struct {
+	dir        string
+	profile    string
+	files      []string
+	address    string
+	isDebug    bool
+	shutdown   time.Duration
+	drainDelay time.Duration
+	tokenKey   string
+	tokenTTL   time.Duration
+	refreshTTL time.Duration
+	adminTTL   time.Duration
+	mysqlHost  string
+	mysqlPort  string
+	mysqlUser  string
+	mysqlPass  string
+	mysqlDb    string
+	mysqlSize  int
+
+	sessionStore    string
+	sessionPath     string
+	sessionCookie   string
+	sessionLifetime time.Duration
+	sessionGC       time.Duration
+	sessionSliding  bool
+	cookieSecure    bool
+	cookieHTTPOnly  bool
+	cookieSameSite  string
+
+	wechatProvider string
+	wechatAppID    string
+	wechatSecret   string
+	wechatEndpoint string
+
+	smsProvider    string
+	smsFile        string
+	smsURL         string
+	smsAPIKey      string
+	smsContentType string
+	smsTemplate    string
+
+	// reloadable
+	corsHosts       []string
+	corsMethods     []string
+	corsHeaders     []string
+	corsCredentials bool
+	corsMaxAge      time.Duration
+	logLevel        string
+	logEncoding     string
+	logOutput       string
+	logMaxSize      int
+	logRotateEvery  time.Duration
+	logMaxBackups   int
+	rateLimit       float64
+	rateBurst       int
+	trustedProxies  []string
+	accessLog       bool
+	accessSample    map[string]float64
+	features        map[string]bool
+	smsLimits       sms.Limits
+}
+
+// configError lists every invalid key found while loading the configuration.
+type configError []string
+
+func (e configError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("server.address", ":17071")
+	v.SetDefault("server.debug", false)
+	v.SetDefault("server.shutdowntimeout", "15s")
+	v.SetDefault("server.draindelay", "0s")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.encoding", "json")
+	v.SetDefault("log.output", "stderr")
+	v.SetDefault("log.rotate.maxsize", 100)
+	v.SetDefault("log.rotate.every", "24h")
+	v.SetDefault("log.rotate.maxbackups", 7)
+	v.SetDefault("log.access.enabled", true)
+	v.SetDefault("middleware.jwt.ttl", "15m")
+	v.SetDefault("middleware.jwt.refreshttl", "720h")
+	v.SetDefault("middleware.jwt.adminttl", "8h")
+	v.SetDefault("middleware.cors.hosts", []string{})
+	v.SetDefault("middleware.cors.methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
+	v.SetDefault("middleware.cors.headers", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"})
+	v.SetDefault("middleware.cors.credentials", true)
+	v.SetDefault("middleware.cors.maxage", "12h")
+	v.SetDefault("middleware.ratelimit.rps", 0)
+	v.SetDefault("middleware.ratelimit.burst", 20)
+	v.SetDefault("server.trustedproxies", []string{})
+	v.SetDefault("mysql.host", "127.0.0.1")
+	v.SetDefault("mysql.port", "3306")
+	v.SetDefault("mysql.user", "root")
+	v.SetDefault("mysql.db", "shop")
+	v.SetDefault("mysql.size", 10)
+	v.SetDefault("session.store", "mysql")
+	v.SetDefault("session.path", "./sessions")
+	v.SetDefault("session.lifetime", "1h")
+	v.SetDefault("session.gcinterval", "10m")
+	v.SetDefault("session.sliding", true)
+	v.SetDefault("session.cookie.name", "userid")
+	v.SetDefault("session.cookie.secure", false)
+	v.SetDefault("session.cookie.httponly", true)
+	v.SetDefault("session.cookie.samesite", "lax")
+	v.SetDefault("wechat.provider", "")
+	v.SetDefault("wechat.endpoint", wechat.DefaultEndpoint)
+	v.SetDefault("sms.provider", "")
+	v.SetDefault("sms.http.contenttype", "application/json")
+	v.SetDefault("sms.http.template", sms.DefaultTemplate)
+	v.SetDefault("sms.codettl", sms.DefaultLimits.CodeTTL.String())
+	v.SetDefault("sms.interval", sms.DefaultLimits.Interval.String())
+	v.SetDefault("sms.perphone", sms.DefaultLimits.PerPhone)
+	v.SetDefault("sms.perip", sms.DefaultLimits.PerIP)
+	v.SetDefault("sms.attempts", sms.DefaultLimits.Attempts)
+}
+
+// readConfiguration resolves the configuration in order of precedence:
+// SHOPAPI_* environment variables, config.<profile>.json, config.json and
+// the built-in defaults. Missing files are skipped; the result is validated.
+func readConfiguration(dir, profile string) (*shopServerConfig, error) {
+	if profile == "" {
+		profile = os.Getenv("SHOPAPI_PROFILE")
+	}
+	if profile == "" {
+		profile = defaultProfile
+	}
+
+	v := viper.New()
+	setDefaults(v)
+
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	var files []string
+	for _, name := range []string{"config.json", "config." + profile + ".json"} {
+		file := filepath.Join(dir, name)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+
+		v.SetConfigFile(file)
+		if err := v.MergeInConfig(); err != nil {
+			return nil, fmt.Errorf("read %s: %v", file, err)
+		}
+		files = append(files, file)
+	}
+
+	features := make(map[string]bool)
+	for name, on := range v.GetStringMap("features") {
+		features[name] = cast.ToBool(on)
+	}
+
+	accessSample := make(map[string]float64)
+	for route, rate := range v.GetStringMap("log.access.sample") {
+		accessSample[route] = cast.ToFloat64(rate)
+	}
+
+	conf := &shopServerConfig{
+		dir:             dir,
+		profile:         profile,
+		files:           files,
+		address:         v.GetString("server.address"),
+		isDebug:         v.GetBool("server.debug"),
+		shutdown:        v.GetDuration("server.shutdowntimeout"),
+		drainDelay:      v.GetDuration("server.draindelay"),
+		tokenKey:        v.GetString("middleware.jwt.tokenkey"),
+		tokenTTL:        v.GetDuration("middleware.jwt.ttl"),
+		refreshTTL:      v.GetDuration("middleware.jwt.refreshttl"),
+		adminTTL:        v.GetDuration("middleware.jwt.adminttl"),
+		mysqlHost:       v.GetString("mysql.host"),
+		mysqlPort:       strings.TrimPrefix(v.GetString("mysql.port"), ":"),
+		mysqlUser:       v.GetString("mysql.user"),
+		mysqlPass:       v.GetString("mysql.pass"),
+		mysqlDb:         v.GetString("mysql.db"),
+		mysqlSize:       v.GetInt("mysql.size"),
+		sessionStore:    v.GetString("session.store"),
+		sessionPath:     v.GetString("session.path"),
+		sessionCookie:   v.GetString("session.cookie.name"),
+		sessionLifetime: v.GetDuration("session.lifetime"),
+		sessionGC:       v.GetDuration("session.gcinterval"),
+		sessionSliding:  v.GetBool("session.sliding"),
+		cookieSecure:    v.GetBool("session.cookie.secure"),
+		cookieHTTPOnly:  v.GetBool("session.cookie.httponly"),
+		cookieSameSite:  strings.ToLower(v.GetString("session.cookie.samesite")),
+		wechatProvider:  strings.ToLower(v.GetString("wechat.provider")),
+		wechatAppID:     v.GetString("wechat.appid"),
+		wechatSecret:    v.GetString("wechat.secret"),
+		wechatEndpoint:  v.GetString("wechat.endpoint"),
+		smsProvider:     strings.ToLower(v.GetString("sms.provider")),
+		smsFile:         v.GetString("sms.file"),
+		smsURL:          v.GetString("sms.http.url"),
+		smsAPIKey:       v.GetString("sms.http.apikey"),
+		smsContentType:  v.GetString("sms.http.contenttype"),
+		smsTemplate:     v.GetString("sms.http.template"),
+		corsHosts:       v.GetStringSlice("middleware.cors.hosts"),
+		corsMethods:     v.GetStringSlice("middleware.cors.methods"),
+		corsHeaders:     v.GetStringSlice("middleware.cors.headers"),
+		corsCredentials: v.GetBool("middleware.cors.credentials"),
+		corsMaxAge:      v.GetDuration("middleware.cors.maxage"),
+		logLevel:        v.GetString("log.level"),
+		logEncoding:     v.GetString("log.encoding"),
+		logOutput:       v.GetString("log.output"),
+		logMaxSize:      v.GetInt("log.rotate.maxsize"),
+		logRotateEvery:  v.GetDuration("log.rotate.every"),
+		logMaxBackups:   v.GetInt("log.rotate.maxbackups"),
+		rateLimit:       v.GetFloat64("middleware.ratelimit.rps"),
+		rateBurst:       v.GetInt("middleware.ratelimit.burst"),
+		trustedProxies:  v.GetStringSlice("server.trustedproxies"),
+		accessLog:       v.GetBool("log.access.enabled"),
+		accessSample:    accessSample,
+		features:        features,
+		smsLimits: sms.Limits{
+			CodeTTL:  v.GetDuration("sms.codettl"),
+			Interval: v.GetDuration("sms.interval"),
+			PerPhone: v.GetInt("sms.perphone"),
+			PerIP:    v.GetInt("sms.perip"),
+			Attempts: v.GetInt("sms.attempts"),
+		},
+	}
+
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+func (conf *shopServerConfig) validate() error {
+	var errs configError
+
+	known := false
+	for _, p := range profiles {
+		known = known || p == conf.profile
+	}
+	if !known {
+		errs = append(errs, fmt.Sprintf("profile: %q is not one of %s", conf.profile, strings.Join(profiles, ", ")))
+	}
+
+	if _, port, err := net.SplitHostPort(conf.address); err != nil {
+		errs = append(errs, fmt.Sprintf("server.address: %q can't be parsed: %v", conf.address, err))
+	} else if !isValidPort(port) {
+		errs = append(errs, fmt.Sprintf("server.address: %q has an invalid port", conf.address))
+	}
+
+	for _, proxy := range conf.trustedProxies {
+		if !handler.IsValidProxy(proxy) {
+			errs = append(errs, fmt.Sprintf("server.trustedproxies: %q is not an IP address or a CIDR network", proxy))
+		}
+	}
+
+	if conf.shutdown <= 0 {
+		errs = append(errs, "server.shutdowntimeout: must be a positive duration such as \"15s\"")
+	}
+
+	if conf.drainDelay < 0 || conf.drainDelay >= conf.shutdown {
+		errs = append(errs, "server.draindelay: must not be negative and must be shorter than server.shutdowntimeout")
+	}
+
+	if conf.tokenKey == "" {
+		errs = append(errs, "middleware.jwt.tokenkey: must not be empty")
+	}
+
+	if conf.tokenTTL <= 0 {
+		errs = append(errs, "middleware.jwt.ttl: must be a positive duration such as \"15m\"")
+	}
+
+	if conf.refreshTTL <= conf.tokenTTL {
+		errs = append(errs, "middleware.jwt.refreshttl: must be longer than middleware.jwt.ttl")
+	}
+
+	if conf.adminTTL <= 0 {
+		errs = append(errs, "middleware.jwt.adminttl: must be a positive duration such as \"8h\"")
+	}
+
+	if conf.mysqlHost == "" {
+		errs = append(errs, "mysql.host: must not be empty")
+	}
+
+	if !isValidPort(conf.mysqlPort) {
+		errs = append(errs, fmt.Sprintf("mysql.port: %q is not a valid port", conf.mysqlPort))
+	}
+
+	if conf.mysqlUser == "" {
+		errs = append(errs, "mysql.user: must not be empty")
+	}
+
+	if conf.mysqlDb == "" {
+		errs = append(errs, "mysql.db: must not be empty")
+	}
+
+	if conf.mysqlSize <= 0 {
+		errs = append(errs, "mysql.size: must be greater than 0")
+	}
+
+	if !utility.IsValidSessionStore(conf.sessionStore) {
+		errs = append(errs, fmt.Sprintf("session.store: %q is not one of memory, file, mysql", conf.sessionStore))
+	}
+
+	if conf.sessionStore == "file" && conf.sessionPath == "" {
+		errs = append(errs, "session.path: must not be empty for the file store")
+	}
+
+	if conf.sessionCookie == "" {
+		errs = append(errs, "session.cookie.name: must not be empty")
+	}
+
+	if conf.sessionLifetime <= 0 {
+		errs = append(errs, "session.lifetime: must be a positive duration such as \"1h\"")
+	}
+
+	if conf.sessionGC <= 0 {
+		errs = append(errs, "session.gcinterval: must be a positive duration such as \"10m\"")
+	}
+
+	if _, ok := sameSiteModes[conf.cookieSameSite]; !ok {
+		errs = append(errs, fmt.Sprintf("session.cookie.samesite: %q is not one of lax, strict, none", conf.cookieSameSite))
+	} else if conf.cookieSameSite == "none" && !conf.cookieSecure {
+		errs = append(errs, "session.cookie.samesite: \"none\" requires session.cookie.secure")
+	}
+
+	switch conf.wechatProvider {
+	case "":
+	case "wechat":
+		if conf.wechatAppID == "" || conf.wechatSecret == "" {
+			errs = append(errs, "wechat.appid, wechat.secret: must not be empty for the wechat provider")
+		}
+	case "fake":
+		if conf.profile == "prod" {
+			errs = append(errs, "wechat.provider: \"fake\" logs anyone in and is refused in prod")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("wechat.provider: %q is not one of wechat, fake or empty", conf.wechatProvider))
+	}
+
+	switch conf.smsProvider {
+	case "":
+	case "http":
+		if _, err := conf.smsSender(); err != nil {
+			errs = append(errs, fmt.Sprintf("sms.http: %v", err))
+		}
+	case "fake":
+		if conf.profile == "prod" {
+			errs = append(errs, "sms.provider: \"fake\" delivers no message and is refused in prod")
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("sms.provider: %q is not one of http, fake or empty", conf.smsProvider))
+	}
+
+	if conf.smsLimits.CodeTTL <= 0 {
+		errs = append(errs, "sms.codettl: must be a positive duration such as \"5m\"")
+	}
+
+	if conf.smsLimits.Interval < 0 {
+		errs = append(errs, "sms.interval: must not be negative")
+	}
+
+	if conf.smsLimits.PerPhone < 0 || conf.smsLimits.PerIP < 0 {
+		errs = append(errs, "sms.perphone, sms.perip: must not be negative")
+	}
+
+	if conf.smsLimits.Attempts < 1 {
+		errs = append(errs, "sms.attempts: must be at least 1")
+	}
+
+	for _, host := range conf.corsHosts {
+		if !handler.IsValidCORSHost(host) {
+			errs = append(errs, fmt.Sprintf("middleware.cors.hosts: %q is not an origin such as \"https://*.example.com\"", host))
+		}
+	}
+
+	if conf.corsCredentials {
+		for _, host := range conf.corsHosts {
+			if host == "*" {
+				errs = append(errs, "middleware.cors.hosts: \"*\" can't be used with middleware.cors.credentials")
+			}
+		}
+	}
+
+	if conf.corsMaxAge < 0 {
+		errs = append(errs, "middleware.cors.maxage: must not be negative")
+	}
+
+	if !log.IsValidLevel(conf.logLevel) {
+		errs = append(errs, fmt.Sprintf("log.level: %q is not a log level", conf.logLevel))
+	}
+
+	for route, rate := range conf.accessSample {
+		if !handler.IsValidSampleRate(rate) {
+			errs = append(errs, fmt.Sprintf("log.access.sample: rate %v of %q is not between 0 and 1", rate, route))
+		}
+	}
+
+	if !log.IsValidEncoding(conf.logEncoding) {
+		errs = append(errs, fmt.Sprintf("log.encoding: %q is not one of json, console", conf.logEncoding))
+	}
+
+	if conf.logMaxSize < 0 {
+		errs = append(errs, "log.rotate.maxsize: must not be negative")
+	}
+
+	if conf.logRotateEvery < 0 {
+		errs = append(errs, "log.rotate.every: must not be negative")
+	}
+
+	if conf.logMaxBackups < 0 {
+		errs = append(errs, "log.rotate.maxbackups: must not be negative")
+	}
+
+	if conf.rateLimit < 0 {
+		errs = append(errs, "middleware.ratelimit.rps: must not be negative")
+	}
+
+	if conf.rateLimit > 0 && conf.rateBurst < 1 {
+		errs = append(errs, "middleware.ratelimit.burst: must be at least 1")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// logConfig returns the settings of the logger.
+func (conf *shopServerConfig) logConfig() log.Config {
+	return log.Config{
+		Level:       conf.logLevel,
+		Encoding:    conf.logEncoding,
+		Output:      conf.logOutput,
+		MaxSize:     conf.logMaxSize,
+		RotateEvery: conf.logRotateEvery,
+		MaxBackups:  conf.logMaxBackups,
+	}
+}
+
+// sessionConfig returns the settings of the session manager.
+func (conf *shopServerConfig) sessionConfig() utility.SessionConfig {
+	return utility.SessionConfig{
+		Store:      conf.sessionStore,
+		Path:       conf.sessionPath,
+		CookieName: conf.sessionCookie,
+		Lifetime:   conf.sessionLifetime,
+		GCInterval: conf.sessionGC,
+		Sliding:    conf.sessionSliding,
+		Secure:     conf.cookieSecure,
+		HTTPOnly:   conf.cookieHTTPOnly,
+		SameSite:   sameSiteModes[conf.cookieSameSite],
+	}
+}
+
+// wechatLogin returns the provider of WeChat login, nil when it is
+// disabled.
+func (conf *shopServerConfig) wechatLogin() wechat.Provider {
+	switch conf.wechatProvider {
+	case "wechat":
+		return wechat.NewOAuth(conf.wechatAppID, conf.wechatSecret, conf.wechatEndpoint)
+	case "fake":
+		return wechat.Fake{}
+	}
+
+	return nil
+}
+
+// smsSender returns the provider of SMS, nil when sending is disabled.
+func (conf *shopServerConfig) smsSender() (sms.Provider, error) {
+	switch conf.smsProvider {
+	case "http":
+		h, err := sms.NewHTTP(conf.smsURL, conf.smsAPIKey, conf.smsContentType, conf.smsTemplate)
+		if err != nil {
+			return nil, err
+		}
+
+		return h, nil
+	case "fake":
+		return &sms.Fake{Path: conf.smsFile}, nil
+	}
+
+	return nil, nil
+}
+
+func isValidPort(port string) bool {
+	n, err := strconv.Atoi(port)
+
+	return err == nil && n > 0 && n < 65536
+}
+
+// printConfiguration writes the resolved configuration as JSON with secrets
+// redacted.
+func printConfiguration(w io.Writer, conf *shopServerConfig) error {
+	secret := func(s string) string {
+		if s == "" {
+			return ""
+		}
+
+		return redacted
+	}
+
+	resolved := map[string]interface{}{
+		"profile": conf.profile,
+		"files":   conf.files,
+		"server": map[string]interface{}{
+			"address":         conf.address,
+			"debug":           conf.isDebug,
+			"shutdowntimeout": conf.shutdown.String(),
+			"draindelay":      conf.drainDelay.String(),
+			"trustedproxies":  conf.trustedProxies,
+		},
+		"log": map[string]interface{}{
+			"level":    conf.logLevel,
+			"encoding": conf.logEncoding,
+			"output":   conf.logOutput,
+			"rotate": map[string]interface{}{
+				"maxsize":    conf.logMaxSize,
+				"every":      conf.logRotateEvery.String(),
+				"maxbackups": conf.logMaxBackups,
+			},
+			"access": map[string]interface{}{
+				"enabled": conf.accessLog,
+				"sample":  conf.accessSample,
+			},
+		},
+		"middleware": map[string]interface{}{
+			"cors": map[string]interface{}{
+				"hosts":       conf.corsHosts,
+				"methods":     conf.corsMethods,
+				"headers":     conf.corsHeaders,
+				"credentials": conf.corsCredentials,
+				"maxage":      conf.corsMaxAge.String(),
+			},
+			"jwt": map[string]interface{}{
+				"tokenkey":   secret(conf.tokenKey),
+				"ttl":        conf.tokenTTL.String(),
+				"refreshttl": conf.refreshTTL.String(),
+				"adminttl":   conf.adminTTL.String(),
+			},
+			"ratelimit": map[string]interface{}{
+				"rps":   conf.rateLimit,
+				"burst": conf.rateBurst,
+			},
+		},
+		"features": conf.features,
+		"session": map[string]interface{}{
+			"store":      conf.sessionStore,
+			"path":       conf.sessionPath,
+			"lifetime":   conf.sessionLifetime.String(),
+			"gcinterval": conf.sessionGC.String(),
+			"sliding":    conf.sessionSliding,
+			"cookie": map[string]interface{}{
+				"name":     conf.sessionCookie,
+				"secure":   conf.cookieSecure,
+				"httponly": conf.cookieHTTPOnly,
+				"samesite": conf.cookieSameSite,
+			},
+		},
+		"wechat": map[string]interface{}{
+			"provider": conf.wechatProvider,
+			"appid":    conf.wechatAppID,
+			"secret":   secret(conf.wechatSecret),
+			"endpoint": conf.wechatEndpoint,
+		},
+		"sms": map[string]interface{}{
+			"provider": conf.smsProvider,
+			"file":     conf.smsFile,
+			"http": map[string]interface{}{
+				"url":         conf.smsURL,
+				"apikey":      secret(conf.smsAPIKey),
+				"contenttype": conf.smsContentType,
+				"template":    conf.smsTemplate,
+			},
+			"codettl":  conf.smsLimits.CodeTTL.String(),
+			"interval": conf.smsLimits.Interval.String(),
+			"perphone": conf.smsLimits.PerPhone,
+			"perip":    conf.smsLimits.PerIP,
+			"attempts": conf.smsLimits.Attempts,
+		},
+		"mysql": map[string]interface{}{
+			"host": conf.mysqlHost,
+			"port": conf.mysqlPort,
+			"user": conf.mysqlUser,
+			"pass": secret(conf.mysqlPass),
+			"db":   conf.mysqlDb,
+			"size": conf.mysqlSize,
+		},
+	}
+
+	out, err := json.MarshalIndent(resolved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(out))
+
+	return err
+}
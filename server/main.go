@@ -1,34 +1,104 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/18        Yusan Kurban
- */
-
-package main
-
-func main() {
-	startServer()
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/18        Yusan Kurban
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"ShopApi/general"
+	"ShopApi/log"
+	"ShopApi/models"
+)
+
+var (
+	configDir   = flag.String("config", "./", "directory holding config.json and config.<profile>.json")
+	profile     = flag.String("profile", "", "configuration profile: dev, test or prod (default $SHOPAPI_PROFILE or dev)")
+	printConfig = flag.Bool("print-config", false, "print the resolved configuration with secrets redacted and exit")
+	createAdmin = flag.String("create-admin", "", "create an admin with this username and the password in $SHOPAPI_ADMIN_PASSWORD, and exit")
+)
+
+func main() {
+	flag.Parse()
+
+	conf, err := readConfiguration(*configDir, *profile)
+	if err != nil {
+		log.Logger.Fatal(err)
+	}
+
+	if *printConfig {
+		if err = printConfiguration(os.Stdout, conf); err != nil {
+			log.Logger.Fatal(err)
+		}
+
+		return
+	}
+
+	if err = log.Init(conf.logConfig()); err != nil {
+		log.Logger.Fatal(err)
+	}
+	defer log.Sync()
+
+	if *createAdmin != "" {
+		if err = newAdmin(conf, *createAdmin, os.Getenv("SHOPAPI_ADMIN_PASSWORD")); err != nil {
+			log.Logger.Fatal(err)
+		}
+
+		return
+	}
+
+	if err = NewApp(conf).Run(); err != nil {
+		log.Logger.Fatal(err)
+	}
+}
+
+// newAdmin creates the admin username with the super admin role. Admins
+// can't sign up, so the first one is created from the command line.
+func newAdmin(conf *shopServerConfig, username, pass string) error {
+	if len(pass) < 8 {
+		return errors.New("SHOPAPI_ADMIN_PASSWORD must hold at least 8 characters")
+	}
+
+	initMysql(conf)
+
+	admin := &models.Admin{Username: username, Name: username}
+	if err := models.AdminService.Create(admin, pass); err != nil {
+		return err
+	}
+
+	if err := models.RoleService.AssignByName(admin.ID, general.RoleSuper); err != nil {
+		return err
+	}
+
+	log.Logger.Info("Admin %s created with id %d and the %s role", admin.Username, admin.ID, general.RoleSuper)
+
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+
+	"ShopApi/general"
+	"ShopApi/handler"
+	"ShopApi/log"
+	"ShopApi/sms"
+)
+
+var (
+	current     atomic.Value // *shopServerConfig
+	reloadLock  sync.Mutex
+	subscribers []func(*shopServerConfig)
+)
+
+// currentConfiguration returns the configuration in effect.
+func currentConfiguration() *shopServerConfig {
+	conf, _ := current.Load().(*shopServerConfig)
+
+	return conf
+}
+
+// subscribeConfiguration registers fn to receive every new configuration.
+// Subscribers must be registered before the first publishConfiguration.
+func subscribeConfiguration(fn func(*shopServerConfig)) {
+	subscribers = append(subscribers, fn)
+}
+
+// publishConfiguration swaps conf in and hands it to every subscriber.
+func publishConfiguration(conf *shopServerConfig) {
+	current.Store(conf)
+
+	for _, fn := range subscribers {
+		fn(conf)
+	}
+}
+
+// applyReloadable pushes the settings that may change at runtime to the
+// packages using them.
+func applyReloadable(conf *shopServerConfig) {
+	log.SetLevel(conf.logLevel)
+	handler.SetCORS(handler.CORSConfig{
+		Hosts:       conf.corsHosts,
+		Methods:     conf.corsMethods,
+		Headers:     conf.corsHeaders,
+		Credentials: conf.corsCredentials,
+		MaxAge:      conf.corsMaxAge,
+	})
+	handler.SetRateLimit(conf.rateLimit, conf.rateBurst)
+	handler.SetTrustedProxies(conf.trustedProxies)
+	handler.SetAccessLog(handler.AccessLogConfig{
+		Enabled: conf.accessLog,
+		Sample:  conf.accessSample,
+	})
+	general.SetFeatures(conf.features)
+	sms.SetLimits(conf.smsLimits)
+}
+
+// watchConfiguration reloads the configuration whenever one of the files it
+// was read from changes on disk.
+func watchConfiguration(conf *shopServerConfig) {
+	for _, file := range conf.files {
+		v := viper.New()
+		v.SetConfigFile(file)
+		v.OnConfigChange(func(e fsnotify.Event) {
+			log.Logger.Info("Configuration file %s changed", e.Name)
+			reloadConfiguration()
+		})
+		v.WatchConfig()
+	}
+}
+
+// reloadConfiguration reads the configuration again and publishes its
+// reloadable settings. An invalid configuration is rejected and the previous
+// one stays in effect.
+func reloadConfiguration() {
+	reloadLock.Lock()
+	defer reloadLock.Unlock()
+
+	old := currentConfiguration()
+
+	conf, err := readConfiguration(old.dir, old.profile)
+	if err != nil {
+		log.Logger.Error("Reject configuration change:", err)
+
+		return
+	}
+
+	if keys := restartRequired(old, conf); len(keys) > 0 {
+		log.Logger.Warn("Changes to %s take effect after a restart", strings.Join(keys, ", "))
+	}
+
+	next := *old
+	next.corsHosts = conf.corsHosts
+	next.corsMethods = conf.corsMethods
+	next.corsHeaders = conf.corsHeaders
+	next.corsCredentials = conf.corsCredentials
+	next.corsMaxAge = conf.corsMaxAge
+	next.logLevel = conf.logLevel
+	next.rateLimit = conf.rateLimit
+	next.rateBurst = conf.rateBurst
+	next.trustedProxies = conf.trustedProxies
+	next.accessLog = conf.accessLog
+	next.accessSample = conf.accessSample
+	next.features = conf.features
+	next.smsLimits = conf.smsLimits
+
+	publishConfiguration(&next)
+}
+
+func restartRequired(old, conf *shopServerConfig) []string {
+	var keys []string
+
+	changed := func(key string, differ bool) {
+		if differ {
+			keys = append(keys, key)
+		}
+	}
+
+	changed("server.address", old.address != conf.address)
+	changed("server.debug", old.isDebug != conf.isDebug)
+	changed("server.shutdowntimeout", old.shutdown != conf.shutdown)
+	changed("server.draindelay", old.drainDelay != conf.drainDelay)
+	changed("middleware.jwt.tokenkey", old.tokenKey != conf.tokenKey)
+	changed("middleware.jwt.ttl", old.tokenTTL != conf.tokenTTL)
+	changed("middleware.jwt.refreshttl", old.refreshTTL != conf.refreshTTL)
+	changed("middleware.jwt.adminttl", old.adminTTL != conf.adminTTL)
+	changed("wechat.provider", old.wechatProvider != conf.wechatProvider)
+	changed("wechat.appid", old.wechatAppID != conf.wechatAppID)
+	changed("wechat.secret", old.wechatSecret != conf.wechatSecret)
+	changed("wechat.endpoint", old.wechatEndpoint != conf.wechatEndpoint)
+	changed("sms.provider", old.smsProvider != conf.smsProvider)
+	changed("sms.file", old.smsFile != conf.smsFile)
+	changed("sms.http", old.smsURL != conf.smsURL || old.smsAPIKey != conf.smsAPIKey || old.smsContentType != conf.smsContentType || old.smsTemplate != conf.smsTemplate)
+	changed("mysql.host", old.mysqlHost != conf.mysqlHost)
+	changed("mysql.port", old.mysqlPort != conf.mysqlPort)
+	changed("mysql.user", old.mysqlUser != conf.mysqlUser)
+	changed("mysql.pass", old.mysqlPass != conf.mysqlPass)
+	changed("mysql.db", old.mysqlDb != conf.mysqlDb)
+	changed("mysql.size", old.mysqlSize != conf.mysqlSize)
+	changed("log.encoding", old.logEncoding != conf.logEncoding)
+	changed("log.output", old.logOutput != conf.logOutput)
+	changed("log.rotate", old.logMaxSize != conf.logMaxSize || old.logRotateEvery != conf.logRotateEvery || old.logMaxBackups != conf.logMaxBackups)
+	changed("session", old.sessionConfig() != conf.sessionConfig())
+
+	return keys
+}
@@ -0,0 +1,334 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package router
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/orm"
+	"ShopApi/utility"
+)
+
+const (
+	owner    = 1001 // owns row rowID of every table
+	stranger = 1002
+	rowID    = 7
+	smsCode  = "123456" // of every phone
+)
+
+// ownedRows is a database/sql driver holding one row, owned by owner, in
+// every table. A query restricted by userid finds it only when restricted
+// to owner; an unrestricted one always finds it, so a handler that forgets
+// the restriction hands the row to anyone. Every login session is active.
+// Reads and writes are recorded.
+type ownedRows struct {
+	mu         sync.Mutex
+	statements []ownedStatement
+}
+
+type ownedStatement struct {
+	query string
+	args  []driver.Value
+}
+
+// boundTo reports whether w is restricted by userid and only to userID.
+func (w ownedStatement) boundTo(userID int64) bool {
+	if !strings.Contains(w.query, "userid") {
+		return false
+	}
+
+	var bound bool
+	for _, a := range w.args {
+		if n, ok := a.(int64); ok && (n == owner || n == stranger) {
+			if n != userID {
+				return false
+			}
+			bound = true
+		}
+	}
+
+	return bound
+}
+
+func (d *ownedRows) Open(string) (driver.Conn, error) { return &ownedConn{d}, nil }
+
+func (d *ownedRows) reset() {
+	d.mu.Lock()
+	d.statements = nil
+	d.mu.Unlock()
+}
+
+func (d *ownedRows) record(query string, args []driver.Value) {
+	d.mu.Lock()
+	d.statements = append(d.statements, ownedStatement{query, args})
+	d.mu.Unlock()
+}
+
+// tableStatements returns the recorded reads of and writes to table, and
+// whether any of them was a write.
+func (d *ownedRows) tableStatements(table string) (found []ownedStatement, wrote bool) {
+	name := regexp.MustCompile("\\b" + table + "\\b")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, w := range d.statements {
+		if name.MatchString(w.query) {
+			found = append(found, w)
+			wrote = wrote || !strings.HasPrefix(strings.TrimSpace(w.query), "SELECT")
+		}
+	}
+
+	return found, wrote
+}
+
+type ownedConn struct{ d *ownedRows }
+
+func (c *ownedConn) Prepare(query string) (driver.Stmt, error) { return &ownedStmt{c.d, query}, nil }
+func (c *ownedConn) Close() error                              { return nil }
+func (c *ownedConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *ownedConn) Commit() error                             { return nil }
+func (c *ownedConn) Rollback() error                           { return nil }
+
+type ownedStmt struct {
+	d     *ownedRows
+	query string
+}
+
+func (s *ownedStmt) Close() error  { return nil }
+func (s *ownedStmt) NumInput() int { return -1 }
+
+func (s *ownedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.record(s.query, args)
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *ownedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.record(s.query, args)
+
+	counting := strings.Contains(strings.ToLower(s.query), "count(")
+	if strings.Contains(s.query, "userid") && !strings.Contains(s.query, "`usersession`") {
+		var mine bool
+		for _, a := range args {
+			if n, ok := a.(int64); ok && n == owner {
+				mine = true
+			}
+		}
+
+		if !mine {
+			if counting {
+				return &countRowSet{}, nil
+			}
+			return &ownedRowSet{}, nil
+		}
+	}
+
+	if counting {
+		return &countRowSet{count: 1}, nil
+	}
+
+	return &ownedRowSet{rows: 1}, nil
+}
+
+// countRowSet answers a query counting rows.
+type countRowSet struct {
+	count int64
+	done  bool
+}
+
+func (r *countRowSet) Columns() []string { return []string{"count"} }
+func (r *countRowSet) Close() error      { return nil }
+
+func (r *countRowSet) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.count
+
+	return nil
+}
+
+type ownedRowSet struct{ rows int }
+
+func (r *ownedRowSet) Columns() []string {
+	return []string{"id", "userid", "productid", "status", "count", "isdefault", "created", "code"}
+}
+
+func (r *ownedRowSet) Close() error { return nil }
+
+func (r *ownedRowSet) Next(dest []driver.Value) error {
+	if r.rows == 0 {
+		return io.EOF
+	}
+	r.rows--
+
+	dest[0], dest[1], dest[2] = int64(rowID), int64(owner), int64(5)
+	dest[3], dest[4], dest[5] = int64(general.OrderUnfinished), int64(1), int64(0)
+	dest[6], dest[7] = time.Now(), smsCode
+
+	return nil
+}
+
+var ownedDriver = &ownedRows{}
+
+func init() {
+	sql.Register("ownedrows", ownedDriver)
+}
+
+// TestCrossUserAccess calls every route acting on a row named in the
+// request body, as its owner and as another user, and every route acting on
+// the rows of the caller, which must only ever read and write those rows.
+func TestCrossUserAccess(t *testing.T) {
+	sqlDB, err := sql.Open("ownedrows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orm.Conn, err = gorm.Open("mysql", sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { orm.Conn = nil }()
+
+	utility.InitToken("test", time.Hour, time.Hour, time.Hour)
+
+	e := echo.New()
+	e.HTTPErrorHandler = general.EchoRestfulErrorHandler
+	InitRouter(e)
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+		table  string // written by the route, or read by a self route
+		self   bool   // acts on the rows of the caller
+	}{
+		{echo.POST, "/carts/delete", `{"id": 7, "productid": 5}`, "carts", false},
+		{echo.POST, "/carts/altercartpro", `{"id": 7, "count": 2}`, "carts", false},
+		{echo.POST, "/contact/change", `{"id": 7, "name": "Li"}`, "contact", false},
+		{echo.POST, "/contact/alter", `{"id": 7}`, "contact", false},
+		{echo.POST, "/orders/getone", `{"id": 7}`, "", false},
+		{echo.POST, "/user/changephone", `{"phone": "13800138000", "code": "` + smsCode + `"}`, "userinfo", true},
+		{echo.POST, "/user/changeinfo", `{"nickname": "Li", "sex": 2}`, "userinfo", true},
+		{echo.GET, "/user/getInfo", ``, "userinfo", true},
+		{echo.POST, "/contact/getaddress", `{}`, "contact", true},
+		{echo.POST, "/orders/get", `{"status": 237}`, "orders", true},
+		{echo.GET, "/carts/browse", ``, "carts", true},
+	}
+
+	call := func(userID uint64, method, path, body string) int {
+		token, _ := utility.NewToken(userID, utility.NewSessionID())
+
+		req := httptest.NewRequest(method, v1Prefix+path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	for _, r := range routes {
+		ownedDriver.reset()
+		if r.self {
+			// The caller has no rows, which some routes answer with not_found.
+			if code := call(stranger, r.method, r.path, r.body); code != http.StatusOK && code != http.StatusNotFound {
+				t.Errorf("%s %s by another user = %d", r.method, r.path, code)
+			}
+			statements, _ := ownedDriver.tableStatements(r.table)
+			for _, w := range statements {
+				if !w.boundTo(stranger) {
+					t.Errorf("%s %s by another user went beyond its own rows: %s %v", r.method, r.path, w.query, w.args)
+				}
+			}
+		} else {
+			if code := call(stranger, r.method, r.path, r.body); code != http.StatusNotFound {
+				t.Errorf("%s %s by another user = %d, want %d", r.method, r.path, code, http.StatusNotFound)
+			}
+			if r.table != "" {
+				if _, wrote := ownedDriver.tableStatements(r.table); wrote {
+					t.Errorf("%s %s by another user wrote to %s", r.method, r.path, r.table)
+				}
+			}
+		}
+
+		ownedDriver.reset()
+		if code := call(owner, r.method, r.path, r.body); code != http.StatusOK {
+			t.Errorf("%s %s by its owner = %d, want %d", r.method, r.path, code, http.StatusOK)
+		}
+		if r.table == "" {
+			continue
+		}
+
+		statements, wrote := ownedDriver.tableStatements(r.table)
+		if len(statements) == 0 || (!r.self && !wrote) {
+			t.Errorf("%s %s by its owner did not touch %s", r.method, r.path, r.table)
+		}
+		for _, w := range statements {
+			if (r.self || !strings.HasPrefix(strings.TrimSpace(w.query), "SELECT")) && !w.boundTo(owner) {
+				t.Errorf("%s %s went beyond the rows of its owner: %s %v", r.method, r.path, w.query, w.args)
+			}
+		}
+	}
+}
+
+// TestAuthRoutesRequireLogin checks that every route documented as needing
+// login refuses requests without a valid credential.
+func TestAuthRoutesRequireLogin(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = general.EchoRestfulErrorHandler
+	InitRouter(e)
+
+	for _, r := range routes {
+		if !r.Auth {
+			continue
+		}
+
+		req := httptest.NewRequest(r.Method, r.Path, strings.NewReader("{}"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer invalid")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without login = %d, want %d", r.Method, r.Path, rec.Code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -1,79 +1,243 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/18        Yusan Kurban
- *     Modify: 2017/07/19         Yang Zhengtian   添加返回收获地址
- *     Modify: 2017/07/20         Yang Zhengtain    添加修改密码
- */
-
-package router
-
-import (
-	"github.com/labstack/echo"
-
-	"ShopApi/handler"
-)
-
-func InitRouter(server *echo.Echo) {
-	if server == nil {
-		panic("[InitRouter], server couldn't be nil")
-	}
-
-	server.POST("/api/v1/user/create", handler.Create)
-	server.POST("/api/v1/user/login", handler.Login)
-	server.GET("/api/v1/user/logout", handler.Logout)
-	server.POST("/api/v1/user/changemobilepass",handler.ChangeMobilePassword)
-	server.POST("/api/v1/user/changeinfo", handler.ChangeUserInfo)
-	server.POST("/api/v1/user/changepass",handler.ChangeMobilePassword,handler.MustLogin)
-	server.POST("/api/vl/user/changephone",handler.Changephone)
-	server.GET("/api/v1/user/getInfo", handler.GetInfo, handler.MustLogin)
-
-
-	server.POST("/api/v1/contact/add", handler.AddAddress, handler.MustLogin)
-	server.POST("/api/vl/contact/alter",handler.Alter)
-	server.POST("/api/v1/contact/change",handler.ChangeAddress)
-	server.POST("/api/v1/contact/getaddress", handler.GetAddress, handler.MustLogin)
-
-	server.POST("/api/v1/products/create",handler.CreateProduct)//创建商品
-	server.POST("/api/v1/product/getinfo",handler.GetProInfo,handler.MustLogin)
-	server.POST("/api/v1/products/changestatus", handler.ChangeProStatus)
-	server.POST("/api/v1/products/getlist", handler.GetProductList)
-	server.POST("/api/v1/products/changecate",handler.ChangeCategories)
-
-	server.POST("/api/v1/orders/get", handler.GetOrders, handler.MustLogin)
-	server.POST("/api/v1/orders/create", handler.CreateOrder, handler.MustLogin)
-	server.POST("/api/v1/orders/getone", handler.GetOneOrder)
-	server.POST("/api/v1/orders/changestatus",handler.ChangeStatus)
-	server.POST("/api/v1/orders/get", handler.GetOrders, handler.MustLogin)
-
-	server.POST("/api/vl/categories/get", handler.GetCategories)
-	server.POST("/api/v1/categories/create",handler.CreateCategories)
-
-	server.POST("/api/v1/carts/delete", handler.Cartsdel, handler.MustLogin)
-	server.POST("/api/vl/carts/altercartpro",handler.AlterCartPro)
-	server.POST("/api/vl/carts/cartsput",handler.CartsPutIn)
-	server.GET("/api/v1/carts/browse", handler.BrowseCart, handler.MustLogin)
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/18        Yusan Kurban
+ *     Modify: 2017/07/19         Yang Zhengtian   添加返回收获地址
+ *     Modify: 2017/07/20         Yang Zhengtain    添加修改密码
+ */
+
+package router
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/handler"
+	"ShopApi/openapi"
+)
+
+const (
+	v1Prefix     = "/api/v1"
+	legacyPrefix = "/api/vl"
+	adminPrefix  = "/admin/api/v1"
+)
+
+var (
+	// legacyPaths were first released under the misspelt /api/vl prefix.
+	// They stay as deprecated aliases of their /api/v1 routes until
+	// legacySunset.
+	legacyPaths = []string{
+		"/user/changephone",
+		"/contact/alter",
+		"/categories/get",
+		"/carts/altercartpro",
+		"/carts/cartsput",
+	}
+
+	legacySince  = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	legacySunset = time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func InitRouter(server *echo.Echo) {
+	if server == nil {
+		panic("[InitRouter], server couldn't be nil")
+	}
+
+	reg := newRegistry(server)
+
+	v1 := reg.group(v1Prefix, handler.APIVersion("v1"))
+
+	v1.POST("/user/create", handler.Create)
+	v1.POST("/user/login", handler.Login)
+	v1.GET("/user/logout", handler.Logout)
+	v1.POST("/user/changemobilepass", handler.ChangeMobilePassword, handler.MustLogin)
+	v1.POST("/user/changeinfo", handler.ChangeUserInfo, handler.MustLogin)
+	v1.POST("/user/changepass", handler.ChangeMobilePassword, handler.MustLogin)
+	v1.POST("/user/changephone", handler.Changephone, handler.MustLogin)
+	v1.GET("/user/getInfo", handler.GetInfo, handler.MustLogin)
+	v1.POST("/user/refresh", handler.RefreshToken)
+	v1.POST("/user/password/verify", handler.VerifyResetCode)
+	v1.POST("/user/password/reset", handler.ResetPassword)
+	v1.POST("/user/sms/login", handler.SMSLogin)
+	v1.POST("/user/wechat/login", handler.WechatLogin)
+	v1.POST("/user/wechat/bind", handler.BindWechat, handler.MustLogin)
+	v1.POST("/user/wechat/unbind", handler.UnbindWechat, handler.MustLogin)
+	v1.POST("/user/wechat/merge", handler.MergeWechat, handler.MustLogin)
+	v1.GET("/user/sessions", handler.GetSessions, handler.MustLogin)
+	v1.POST("/user/sessions/revoke", handler.RevokeSession, handler.MustLogin)
+	v1.POST("/user/sessions/revokeall", handler.RevokeAllSessions, handler.MustLogin)
+
+	v1.POST("/sms/send", handler.SendSMS)
+
+	v1.POST("/contact/add", handler.AddAddress, handler.MustLogin)
+	v1.POST("/contact/alter", handler.Alter, handler.MustLogin)
+	v1.POST("/contact/change", handler.ChangeAddress, handler.MustLogin)
+	v1.POST("/contact/getaddress", handler.GetAddress, handler.MustLogin)
+
+	v1.POST("/product/getinfo", handler.GetProInfo, handler.MustLogin)
+	v1.POST("/products/getlist", handler.GetProductList)
+
+	v1.POST("/orders/get", handler.GetOrders, handler.MustLogin)
+	v1.POST("/orders/create", handler.CreateOrder, handler.MustLogin)
+	v1.POST("/orders/getone", handler.GetOneOrder, handler.MustLogin)
+
+	v1.POST("/categories/get", handler.GetCategories)
+
+	v1.POST("/carts/delete", handler.Cartsdel, handler.MustLogin)
+	v1.POST("/carts/altercartpro", handler.AlterCartPro, handler.MustLogin)
+	v1.POST("/carts/cartsput", handler.CartsPutIn, handler.MustLogin)
+	v1.GET("/carts/browse", handler.BrowseCart, handler.MustLogin)
+
+	admin := reg.group(adminPrefix, handler.APIVersion("v1"))
+	can := handler.RequirePermission
+
+	admin.POST("/login", handler.AdminLogin)
+	admin.POST("/logout", handler.AdminLogout, handler.MustAdmin)
+	admin.POST("/password", handler.AdminChangePassword, handler.MustAdmin)
+	admin.GET("/profile", handler.AdminProfile, handler.MustAdmin)
+
+	admin.POST("/products/create", handler.CreateProduct, handler.MustAdmin, can(general.PermProductWrite)) //创建商品
+	admin.POST("/products/changestatus", handler.ChangeProStatus, handler.MustAdmin, can(general.PermProductWrite))
+	admin.POST("/products/changecate", handler.ChangeCategories, handler.MustAdmin, can(general.PermProductWrite))
+
+	admin.POST("/categories/create", handler.CreateCategories, handler.MustAdmin, can(general.PermCategoryWrite))
+
+	admin.POST("/orders/changestatus", handler.ChangeStatus, handler.MustAdmin, can(general.PermOrderWrite))
+
+	admin.POST("/users/suspend", handler.SuspendUser, handler.MustAdmin, can(general.PermUserManage))
+	admin.POST("/users/reactivate", handler.ReactivateUser, handler.MustAdmin, can(general.PermUserManage))
+	admin.POST("/users/statushistory", handler.GetUserStatusHistory, handler.MustAdmin, can(general.PermUserManage))
+
+	admin.GET("/roles/get", handler.GetRoles, handler.MustAdmin, can(general.PermRoleManage))
+	admin.POST("/roles/create", handler.CreateRole, handler.MustAdmin, can(general.PermRoleManage))
+	admin.POST("/roles/change", handler.ChangeRole, handler.MustAdmin, can(general.PermRoleManage))
+	admin.POST("/roles/delete", handler.DeleteRole, handler.MustAdmin, can(general.PermRoleManage))
+	admin.POST("/roles/assign", handler.AssignRole, handler.MustAdmin, can(general.PermRoleManage))
+	admin.POST("/roles/unassign", handler.UnassignRole, handler.MustAdmin, can(general.PermRoleManage))
+	admin.POST("/roles/getbyadmin", handler.GetAdminRoles, handler.MustAdmin, can(general.PermRoleManage))
+
+	legacy := reg.group(legacyPrefix, handler.APIVersion("v1"),
+		handler.Deprecated(legacyPrefix, v1Prefix, legacySince, legacySunset))
+	for _, path := range legacyPaths {
+		legacy.alias(v1, path)
+	}
+
+	root := reg.root()
+
+	root.GET("/metrics", handler.Metrics)
+	root.GET("/healthz", handler.Healthz)
+	root.GET("/readyz", handler.Readyz)
+
+	root.GET("/api/docs/openapi.json", openapi.JSON(Spec()))
+	root.GET("/api/docs", openapi.Page)
+}
+
+// matcher is implemented by both echo.Echo and echo.Group.
+type matcher interface {
+	Match(methods []string, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc)
+}
+
+// registry sets up the routes of a server. Echo lets a route registered
+// twice silently replace the first one; registry panics instead, so that
+// the mistake is caught at startup.
+type registry struct {
+	server *echo.Echo
+	seen   map[string]bool
+}
+
+// routeGroup is a set of routes sharing a prefix and middleware.
+type routeGroup struct {
+	*registry
+	target matcher
+	prefix string
+	routes map[string]route // by "METHOD path", relative to prefix
+}
+
+type route struct {
+	handler    echo.HandlerFunc
+	middleware []echo.MiddlewareFunc
+}
+
+func newRegistry(server *echo.Echo) *registry {
+	return &registry{server: server, seen: make(map[string]bool)}
+}
+
+// group returns an echo route group under prefix.
+func (r *registry) group(prefix string, m ...echo.MiddlewareFunc) *routeGroup {
+	return r.newGroup(r.server.Group(prefix, m...), prefix)
+}
+
+// root returns the routes outside any group.
+func (r *registry) root() *routeGroup {
+	return r.newGroup(r.server, "")
+}
+
+func (r *registry) newGroup(target matcher, prefix string) *routeGroup {
+	return &routeGroup{
+		registry: r,
+		target:   target,
+		prefix:   prefix,
+		routes:   make(map[string]route),
+	}
+}
+
+func (g *routeGroup) GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	g.add(echo.GET, path, h, m...)
+}
+
+func (g *routeGroup) POST(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	g.add(echo.POST, path, h, m...)
+}
+
+func (g *routeGroup) add(method, path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) {
+	key := method + " " + g.prefix + path
+	if g.seen[key] {
+		panic(fmt.Sprintf("[InitRouter], %s registered twice", key))
+	}
+	g.seen[key] = true
+
+	g.routes[method+" "+path] = route{handler: h, middleware: m}
+	g.target.Match([]string{method}, path, h, m...)
+}
+
+// alias registers the routes of from at path in g as well.
+func (g *routeGroup) alias(from *routeGroup, path string) {
+	var found bool
+
+	for _, method := range []string{echo.GET, echo.POST} {
+		if r, ok := from.routes[method+" "+path]; ok {
+			g.add(method, path, r.handler, r.middleware...)
+			found = true
+		}
+	}
+
+	if !found {
+		panic(fmt.Sprintf("[InitRouter], %s%s aliases no route", from.prefix, path))
+	}
+}
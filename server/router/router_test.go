@@ -0,0 +1,191 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+
+	"ShopApi/general"
+	"ShopApi/handler"
+	"ShopApi/utility"
+)
+
+func TestSpecCoversRoutes(t *testing.T) {
+	e := echo.New()
+	InitRouter(e)
+
+	spec := Spec()
+	registered := make(map[string]bool)
+
+	for _, r := range e.Routes() {
+		// Every echo group adds a catch-all that answers 404.
+		if strings.HasSuffix(r.Path, "/*") {
+			continue
+		}
+
+		registered[r.Method+" "+r.Path] = true
+
+		if !spec.Has(r.Method, r.Path) {
+			t.Errorf("%s %s is registered but missing from the OpenAPI spec", r.Method, r.Path)
+		}
+	}
+
+	for _, op := range spec.Operations() {
+		if !registered[op] {
+			t.Errorf("%s is in the OpenAPI spec but not registered", op)
+		}
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	e := echo.New()
+	InitRouter(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/api/docs/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/docs/openapi.json = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string `json:"required"`
+				Properties map[string]struct {
+					Pattern   string   `json:"pattern"`
+					MinLength *float64 `json:"minLength"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode spec: %v", err)
+	}
+
+	register := doc.Components.Schemas["Register"]
+	if len(register.Required) != 3 {
+		t.Errorf("Register requires %v, want mobile, pass and code", register.Required)
+	}
+	if register.Properties["mobile"].Pattern == "" {
+		t.Error("the mobile tag of Register.mobile is not documented")
+	}
+	if register.Properties["code"].Pattern == "" {
+		t.Error("the number tag of Register.code is not documented")
+	}
+	if min := register.Properties["pass"].MinLength; min == nil || *min != 6 {
+		t.Errorf("Register.pass minLength = %v, want 6", min)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/api/docs", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /api/docs = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestLegacyAlias(t *testing.T) {
+	e := echo.New()
+	InitRouter(e)
+
+	req := httptest.NewRequest(echo.POST, "/api/vl/categories/get", strings.NewReader("{"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code == http.StatusNotFound {
+		t.Fatal("the /api/vl alias is not routed")
+	}
+
+	header := rec.Header()
+	if header.Get("Deprecation") == "" || header.Get("Sunset") == "" {
+		t.Errorf("deprecation headers missing: %v", header)
+	}
+	if link := header.Get("Link"); link != `</api/v1/categories/get>; rel="successor-version"` {
+		t.Errorf("Link = %q", link)
+	}
+	if v := header.Get("X-API-Version"); v != "v1" {
+		t.Errorf("X-API-Version = %q, want v1", v)
+	}
+}
+
+func TestDuplicateRoute(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a route twice didn't panic")
+		}
+	}()
+
+	g := newRegistry(echo.New()).group(v1Prefix)
+	g.POST("/orders/get", handler.GetOrders)
+	g.POST("/orders/get", handler.GetOrders)
+}
+
+func TestAdminRoutes(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = general.EchoRestfulErrorHandler
+	InitRouter(e)
+
+	utility.InitToken("test", time.Hour, time.Hour, time.Hour)
+	userToken, _ := utility.NewToken(1, utility.NewSessionID())
+
+	for _, path := range []string{
+		"/products/create",
+		"/products/changestatus",
+		"/products/changecate",
+		"/categories/create",
+		"/orders/changestatus",
+	} {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(echo.POST, v1Prefix+path, strings.NewReader("{}")))
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("POST %s%s = %d, want %d", v1Prefix, path, rec.Code, http.StatusNotFound)
+		}
+
+		for _, auth := range []string{"", "Bearer " + userToken} {
+			req := httptest.NewRequest(echo.POST, adminPrefix+path, strings.NewReader("{}"))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if auth != "" {
+				req.Header.Set(echo.HeaderAuthorization, auth)
+			}
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("POST %s%s with %q = %d, want %d", adminPrefix, path, auth, rec.Code, http.StatusUnauthorized)
+			}
+		}
+	}
+}
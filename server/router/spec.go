@@ -0,0 +1,127 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package router
+
+import (
+	"ShopApi/general"
+	"ShopApi/handler"
+	"ShopApi/models"
+	"ShopApi/openapi"
+)
+
+// routes documents every route set up by InitRouter but the deprecated
+// aliases, which Spec adds. TestSpecCoversRoutes fails when one is missing.
+var routes = []openapi.Route{
+	{Method: "POST", Path: "/api/v1/user/create", Tag: "user", Summary: "注册，需先发送 register 验证码", Request: handler.Register{}},
+	{Method: "POST", Path: "/api/v1/user/login", Tag: "user", Summary: "登录，token 为 true 时返回访问令牌", Request: handler.LoginRequest{}, Response: handler.TokenResp{}},
+	{Method: "GET", Path: "/api/v1/user/logout", Tag: "user", Summary: "登出"},
+	{Method: "POST", Path: "/api/v1/user/changemobilepass", Tag: "user", Summary: "修改密码", Auth: true, Request: models.ConUsers{}},
+	{Method: "POST", Path: "/api/v1/user/changeinfo", Tag: "user", Summary: "修改用户信息，手机号通过 changephone 修改", Auth: true, Request: models.ChangeInfo{}},
+	{Method: "POST", Path: "/api/v1/user/changepass", Tag: "user", Summary: "修改密码", Auth: true, Request: models.ConUsers{}},
+	{Method: "POST", Path: "/api/v1/user/changephone", Tag: "user", Summary: "修改手机号，需 rebind 短信验证码", Auth: true, Request: models.ChangePhone{}},
+	{Method: "GET", Path: "/api/v1/user/getInfo", Tag: "user", Summary: "用户信息", Auth: true, Response: models.UserInfo{}},
+	{Method: "POST", Path: "/api/v1/user/refresh", Tag: "user", Summary: "用刷新令牌换取新的令牌", Request: handler.RefreshReq{}, Response: handler.TokenResp{}},
+	{Method: "POST", Path: "/api/v1/user/password/verify", Tag: "user", Summary: "找回密码：用 reset 验证码换取重置凭证", Request: models.VerifyReset{}, Response: handler.ResetTokenResp{}},
+	{Method: "POST", Path: "/api/v1/user/password/reset", Tag: "user", Summary: "找回密码：用重置凭证设置新密码，所有登录失效", Request: models.ResetPassword{}},
+	{Method: "POST", Path: "/api/v1/user/sms/login", Tag: "user", Summary: "短信验证码登录，首次登录时创建账号", Request: models.SMSLogin{}, Response: handler.TokenResp{}},
+	{Method: "POST", Path: "/api/v1/user/wechat/login", Tag: "user", Summary: "微信登录，首次登录时创建账号", Request: models.WechatLogin{}, Response: handler.TokenResp{}},
+	{Method: "POST", Path: "/api/v1/user/wechat/bind", Tag: "user", Summary: "绑定微信", Auth: true, Request: models.WechatCode{}},
+	{Method: "POST", Path: "/api/v1/user/wechat/unbind", Tag: "user", Summary: "解绑微信", Auth: true},
+	{Method: "POST", Path: "/api/v1/user/wechat/merge", Tag: "user", Summary: "把微信登录创建的账号合并到当前账号", Auth: true, Request: models.WechatCode{}},
+	{Method: "GET", Path: "/api/v1/user/sessions", Tag: "user", Summary: "登录设备列表", Auth: true, Response: []models.UserSession{}},
+	{Method: "POST", Path: "/api/v1/user/sessions/revoke", Tag: "user", Summary: "注销一个登录设备", Auth: true, Request: handler.SessionReq{}},
+	{Method: "POST", Path: "/api/v1/user/sessions/revokeall", Tag: "user", Summary: "注销其他所有登录设备", Auth: true},
+
+	{Method: "POST", Path: "/api/v1/sms/send", Tag: "sms", Summary: "发送短信验证码", Request: models.SendSMS{}, Response: handler.SMSSent{}},
+
+	{Method: "POST", Path: "/api/v1/contact/add", Tag: "contact", Summary: "添加收货地址", Auth: true, Request: models.OrmContact{}},
+	{Method: "POST", Path: "/api/v1/contact/alter", Tag: "contact", Summary: "设为默认地址", Auth: true, Request: models.Contact{}},
+	{Method: "POST", Path: "/api/v1/contact/change", Tag: "contact", Summary: "修改收货地址", Auth: true, Request: models.ChangeAddress{}},
+	{Method: "POST", Path: "/api/v1/contact/getaddress", Tag: "contact", Summary: "收货地址列表", Auth: true, List: true, Request: models.OrmContact{}, Response: []models.AddressGet{}},
+
+	{Method: "POST", Path: "/api/v1/product/getinfo", Tag: "products", Summary: "商品详情", Auth: true, Request: models.ConProductID{}, Response: models.Product{}},
+	{Method: "POST", Path: "/api/v1/products/getlist", Tag: "products", Summary: "分类下的商品列表", List: true, Request: models.ConProduct{}, Response: []models.GetProList{}},
+
+	{Method: "POST", Path: "/api/v1/orders/get", Tag: "orders", Summary: "订单列表", Auth: true, List: true, Request: models.OrmOrders{}, Response: []models.Orders{}},
+	{Method: "POST", Path: "/api/v1/orders/create", Tag: "orders", Summary: "下单", Auth: true, Request: models.RegisterOrder{}},
+	{Method: "POST", Path: "/api/v1/orders/getone", Tag: "orders", Summary: "订单详情", Auth: true, Request: models.OrmOrders{}, Response: models.OrmOrders{}},
+
+	{Method: "POST", Path: "/api/v1/categories/get", Tag: "categories", Summary: "子分类列表", List: true, Request: models.OrmCategories{}, Response: []models.Categories{}},
+
+	{Method: "POST", Path: "/api/v1/carts/delete", Tag: "carts", Summary: "移出购物车", Auth: true, Request: models.ConCartsItem{}},
+	{Method: "POST", Path: "/api/v1/carts/altercartpro", Tag: "carts", Summary: "修改购物车商品", Auth: true, Request: models.ConCartsItem{}},
+	{Method: "POST", Path: "/api/v1/carts/cartsput", Tag: "carts", Summary: "加入购物车", Auth: true, Request: models.ConCarts{}},
+	{Method: "GET", Path: "/api/v1/carts/browse", Tag: "carts", Summary: "购物车", Auth: true, List: true, Response: []models.ConCarts{}},
+
+	{Method: "POST", Path: "/admin/api/v1/login", Tag: "admin", Summary: "管理员登录", Request: models.AdminLogin{}, Response: handler.TokenResp{}},
+	{Method: "POST", Path: "/admin/api/v1/logout", Tag: "admin", Summary: "管理员登出", Admin: true},
+	{Method: "POST", Path: "/admin/api/v1/password", Tag: "admin", Summary: "修改管理员密码", Admin: true, Request: models.AdminPassword{}},
+	{Method: "GET", Path: "/admin/api/v1/profile", Tag: "admin", Summary: "管理员信息", Admin: true, Response: models.Admin{}},
+
+	{Method: "POST", Path: "/admin/api/v1/products/create", Tag: "products", Summary: "创建商品", Admin: true, Permission: general.PermProductWrite, Request: models.ConProduct{}},
+	{Method: "POST", Path: "/admin/api/v1/products/changestatus", Tag: "products", Summary: "商品上下架", Admin: true, Permission: general.PermProductWrite, Request: models.ConProductStatus{}},
+	{Method: "POST", Path: "/admin/api/v1/products/changecate", Tag: "products", Summary: "修改商品分类", Admin: true, Permission: general.PermProductWrite, Request: models.ConProductCate{}},
+
+	{Method: "POST", Path: "/admin/api/v1/categories/create", Tag: "categories", Summary: "创建分类", Admin: true, Permission: general.PermCategoryWrite, Request: models.CreateCat{}},
+
+	{Method: "POST", Path: "/admin/api/v1/orders/changestatus", Tag: "orders", Summary: "修改订单状态，取消订单还需要 order:refund 权限", Admin: true, Permission: general.PermOrderWrite, Request: handler.ChangStatus{}},
+
+	{Method: "POST", Path: "/admin/api/v1/users/suspend", Tag: "admin", Summary: "停用用户，until 为空时不会自动恢复", Admin: true, Permission: general.PermUserManage, Request: models.SuspendUser{}},
+	{Method: "POST", Path: "/admin/api/v1/users/reactivate", Tag: "admin", Summary: "恢复用户", Admin: true, Permission: general.PermUserManage, Request: models.ReactivateUser{}},
+	{Method: "POST", Path: "/admin/api/v1/users/statushistory", Tag: "admin", Summary: "用户状态变更记录", Admin: true, Permission: general.PermUserManage, Request: models.ConUserHistory{}, Response: []models.UserStatusHistory{}},
+
+	{Method: "GET", Path: "/admin/api/v1/roles/get", Tag: "admin", Summary: "角色列表", Admin: true, Permission: general.PermRoleManage, Response: []models.Role{}},
+	{Method: "POST", Path: "/admin/api/v1/roles/create", Tag: "admin", Summary: "创建角色", Admin: true, Permission: general.PermRoleManage, Request: models.CreateRole{}, Response: models.Role{}},
+	{Method: "POST", Path: "/admin/api/v1/roles/change", Tag: "admin", Summary: "修改角色权限", Admin: true, Permission: general.PermRoleManage, Request: models.ChangeRole{}},
+	{Method: "POST", Path: "/admin/api/v1/roles/delete", Tag: "admin", Summary: "删除角色", Admin: true, Permission: general.PermRoleManage, Request: models.ConRole{}},
+	{Method: "POST", Path: "/admin/api/v1/roles/assign", Tag: "admin", Summary: "为管理员分配角色", Admin: true, Permission: general.PermRoleManage, Request: models.AssignRole{}},
+	{Method: "POST", Path: "/admin/api/v1/roles/unassign", Tag: "admin", Summary: "收回管理员的角色", Admin: true, Permission: general.PermRoleManage, Request: models.AssignRole{}},
+	{Method: "POST", Path: "/admin/api/v1/roles/getbyadmin", Tag: "admin", Summary: "管理员的角色", Admin: true, Permission: general.PermRoleManage, Request: models.ConAdminRoles{}, Response: []models.Role{}},
+
+	{Method: "GET", Path: "/metrics", Tag: "ops", Summary: "Prometheus 指标", Raw: true, ContentType: "text/plain"},
+	{Method: "GET", Path: "/healthz", Tag: "ops", Summary: "存活探测", Raw: true, Response: map[string]string{}},
+	{Method: "GET", Path: "/readyz", Tag: "ops", Summary: "就绪探测，依赖不可用时返回 503", Raw: true, Response: handler.Readiness{}},
+
+	{Method: "GET", Path: "/api/docs/openapi.json", Tag: "docs", Summary: "OpenAPI 文档", Raw: true},
+	{Method: "GET", Path: "/api/docs", Tag: "docs", Summary: "接口文档页面", Raw: true, ContentType: "text/html"},
+}
+
+// Spec returns the OpenAPI document of the API.
+func Spec() *openapi.Document {
+	all := append([]openapi.Route(nil), routes...)
+
+	for _, path := range legacyPaths {
+		for _, r := range routes {
+			if r.Path == v1Prefix+path {
+				r.Path = legacyPrefix + path
+				r.Deprecated = true
+				r.Summary += "（已弃用，请改用 " + v1Prefix + path + "）"
+				all = append(all, r)
+			}
+		}
+	}
+
+	return openapi.New("ShopApi", "v1", general.SessionUserID, all)
+}
@@ -0,0 +1,125 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate is the request body posted by HTTP unless configured
+// otherwise.
+const DefaultTemplate = `{"phone":{{json .Phone}},"purpose":{{json .Purpose}},"code":{{json .Code}},"text":{{json .Text}}}`
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+
+		return string(b), err
+	},
+	"query": url.QueryEscape,
+}
+
+// HTTP posts messages to the API of an SMS gateway. The body is rendered
+// from a text/template over the Message, so that most gateways can be
+// reached by configuration alone; the functions json and query quote a
+// value for a JSON or a form body.
+type HTTP struct {
+	URL         string
+	APIKey      string
+	ContentType string
+	Body        *template.Template
+	Client      *http.Client
+}
+
+// NewHTTP returns a provider posting the body rendered from tmpl, or
+// DefaultTemplate when it is empty, to rawURL. A non-empty apiKey is sent
+// as a bearer token.
+func NewHTTP(rawURL, apiKey, contentType, tmpl string) (*HTTP, error) {
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("sms: %q is not an http(s) URL", rawURL)
+	}
+
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+
+	body, err := template.New("sms").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("sms: template: %v", err)
+	}
+
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	return &HTTP{
+		URL:         rawURL,
+		APIKey:      apiKey,
+		ContentType: contentType,
+		Body:        body,
+		Client:      &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+func (h *HTTP) Send(ctx context.Context, m *Message) error {
+	var body bytes.Buffer
+
+	if err := h.Body.Execute(&body, m); err != nil {
+		return fmt.Errorf("sms: render body: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", h.ContentType)
+	if h.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+
+	resp, err := h.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("sms: send: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// drain the body so that the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sms: send: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package sms
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Limits bound how codes are sent and checked.
+type Limits struct {
+	// CodeTTL is how long a code stays valid.
+	CodeTTL time.Duration
+	// Interval is the shortest time between two codes sent to a phone.
+	Interval time.Duration
+	// PerPhone bounds the codes sent to a phone per day and PerIP those
+	// requested by a client IP per hour; zero means no bound.
+	PerPhone int
+	PerIP    int
+	// Attempts is the number of wrong guesses that invalidate a code.
+	Attempts int
+}
+
+// DefaultLimits are in effect until SetLimits is called.
+var DefaultLimits = Limits{
+	CodeTTL:  5 * time.Minute,
+	Interval: time.Minute,
+	PerPhone: 10,
+	PerIP:    30,
+	Attempts: 5,
+}
+
+var limits atomic.Value
+
+func init() {
+	limits.Store(DefaultLimits)
+}
+
+// SetLimits replaces the limits in effect.
+func SetLimits(l Limits) {
+	limits.Store(l)
+}
+
+// CurrentLimits returns the limits in effect.
+func CurrentLimits() Limits {
+	return limits.Load().(Limits)
+}
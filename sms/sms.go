@@ -0,0 +1,123 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package sms
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+)
+
+// Purposes of verification codes. A code only verifies for the purpose it
+// was sent for.
+const (
+	PurposeRegister = "register"
+	PurposeLogin    = "login"
+	PurposeReset    = "reset"
+	PurposeRebind   = "rebind"
+)
+
+// Purposes lists every purpose.
+var Purposes = []string{PurposeRegister, PurposeLogin, PurposeReset, PurposeRebind}
+
+var texts = map[string]string{
+	PurposeRegister: "您正在注册账号，验证码 %s，%d 分钟内有效。",
+	PurposeLogin:    "您正在登录，验证码 %s，%d 分钟内有效。",
+	PurposeReset:    "您正在找回密码，验证码 %s，%d 分钟内有效，请勿泄露给他人。",
+	PurposeRebind:   "您正在更换绑定的手机号，验证码 %s，%d 分钟内有效。",
+}
+
+// ErrUnavailable is returned when no provider is configured.
+var ErrUnavailable = errors.New("sms: sending is not configured")
+
+// IsPurpose reports whether purpose is one of Purposes.
+func IsPurpose(purpose string) bool {
+	_, ok := texts[purpose]
+
+	return ok
+}
+
+// Message is a verification code on its way to a phone.
+type Message struct {
+	Phone   string
+	Purpose string
+	Code    string
+	TTL     time.Duration
+}
+
+// Minutes returns how many minutes the code stays valid.
+func (m *Message) Minutes() int {
+	return int((m.TTL + time.Minute - 1) / time.Minute)
+}
+
+// Text returns the text sent to the user.
+func (m *Message) Text() string {
+	return fmt.Sprintf(texts[m.Purpose], m.Code, m.Minutes())
+}
+
+// Provider delivers messages to phones.
+type Provider interface {
+	Send(ctx context.Context, m *Message) error
+}
+
+var provider atomic.Value
+
+type holder struct{ p Provider }
+
+// Use sets the provider used by Send; nil disables sending.
+func Use(p Provider) {
+	provider.Store(holder{p})
+}
+
+// Enabled reports whether a provider is configured.
+func Enabled() bool {
+	h, _ := provider.Load().(holder)
+
+	return h.p != nil
+}
+
+// Send delivers m through the configured provider.
+func Send(ctx context.Context, m *Message) error {
+	h, _ := provider.Load().(holder)
+	if h.p == nil {
+		return ErrUnavailable
+	}
+
+	return h.p.Send(ctx, m)
+}
+
+// NewCode returns a random code of six digits.
+func NewCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%06d", n), nil
+}
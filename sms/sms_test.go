@@ -0,0 +1,128 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package sms
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var message = &Message{Phone: "13800000000", Purpose: PurposeLogin, Code: "012345", TTL: 5 * time.Minute}
+
+func TestHTTPSend(t *testing.T) {
+	var body, auth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body, auth = string(b), r.Header.Get("Authorization")
+
+		if r.FormValue("fail") != "" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	h, err := NewHTTP(server.URL, "key", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = h.Send(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"phone":"13800000000","purpose":"login","code":"012345","text":"您正在登录，验证码 012345，5 分钟内有效。"}`
+	if body != want || auth != "Bearer key" {
+		t.Errorf("posted %s with %q, want %s", body, auth, want)
+	}
+
+	h, err = NewHTTP(server.URL+"?fail=1", "", "application/x-www-form-urlencoded", "mobile={{query .Phone}}&tpl=1&code={{.Code}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Send(context.Background(), message); err == nil {
+		t.Error("Send succeeded on a 502")
+	}
+	if body != "mobile=13800000000&tpl=1&code=012345" || auth != "" {
+		t.Errorf("posted %s with %q", body, auth)
+	}
+
+	for _, bad := range [][2]string{{"ftp://example.com", ""}, {"https://example.com", "{{.Phone"}} {
+		if _, err = NewHTTP(bad[0], "", "", bad[1]); err == nil {
+			t.Errorf("NewHTTP(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	Use(nil)
+	if err := Send(context.Background(), message); err != ErrUnavailable || Enabled() {
+		t.Errorf("Send without a provider = %v, want ErrUnavailable", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "sms.log")
+
+	Use(&Fake{Path: path})
+	defer Use(nil)
+
+	for i := 0; i < 2; i++ {
+		if err := Send(context.Background(), message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "\t13800000000\tlogin\t012345\t"+message.Text()) {
+		t.Errorf("Fake wrote %q", b)
+	}
+}
+
+func TestNewCode(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for i := 0; i < 100; i++ {
+		code, err := NewCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+			t.Fatalf("NewCode() = %q, want six digits", code)
+		}
+		seen[code] = true
+	}
+
+	if len(seen) < 90 {
+		t.Errorf("NewCode returned %d distinct codes out of 100", len(seen))
+	}
+}
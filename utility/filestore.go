@@ -0,0 +1,176 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package utility
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const fileStoreSuffix = ".sess"
+
+// fileStore keeps one file per session under a directory. Each file holds
+// the expiry as a unix timestamp followed by the session data.
+type fileStore struct {
+	dir string
+}
+
+func newFileStore(conf SessionConfig) (SessionStore, error) {
+	if conf.Path == "" {
+		return nil, errors.New("session: file store needs a path")
+	}
+
+	if err := os.MkdirAll(conf.Path, 0700); err != nil {
+		return nil, err
+	}
+
+	return &fileStore{dir: conf.Path}, nil
+}
+
+// file maps sid to a file name, hex encoding it so that a forged cookie
+// can't escape the directory.
+func (fs *fileStore) file(sid string) string {
+	return filepath.Join(fs.dir, hex.EncodeToString([]byte(sid))+fileStoreSuffix)
+}
+
+func (fs *fileStore) Load(sid string) ([]byte, time.Time, error) {
+	content, err := ioutil.ReadFile(fs.file(sid))
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, ErrSessionNotFound
+	}
+
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if len(content) < 8 {
+		return nil, time.Time{}, ErrSessionNotFound
+	}
+
+	expires := time.Unix(int64(binary.BigEndian.Uint64(content)), 0)
+
+	return content[8:], expires, nil
+}
+
+// Save writes to a temporary file first so a concurrent Load never sees a
+// partial session.
+func (fs *fileStore) Save(sid string, data []byte, expires time.Time) error {
+	content := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(content, uint64(expires.Unix()))
+	copy(content[8:], data)
+
+	tmp, err := ioutil.TempFile(fs.dir, "tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fs.file(sid))
+}
+
+func (fs *fileStore) Delete(sid string) error {
+	err := os.Remove(fs.file(sid))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (fs *fileStore) GC() error {
+	names, err := fs.sessions()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		sid, err := hex.DecodeString(strings.TrimSuffix(name, fileStoreSuffix))
+		if err != nil {
+			continue
+		}
+
+		_, expires, err := fs.Load(string(sid))
+		if err == nil && !expires.After(now) {
+			fs.Delete(string(sid))
+		}
+	}
+
+	return nil
+}
+
+func (fs *fileStore) Count() (int, error) {
+	names, err := fs.sessions()
+
+	return len(names), err
+}
+
+func (fs *fileStore) Ping(context.Context) error {
+	info, err := os.Stat(fs.dir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return errors.New("session: file store path is not a directory")
+	}
+
+	return nil
+}
+
+func (fs *fileStore) sessions() ([]string, error) {
+	infos, err := ioutil.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), fileStoreSuffix) {
+			names = append(names, info.Name())
+		}
+	}
+
+	return names, nil
+}
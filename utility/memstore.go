@@ -0,0 +1,101 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package utility
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// memoryStore keeps sessions in process memory, so they are lost on restart.
+// It is meant for development and tests.
+type memoryStore struct {
+	lock     sync.RWMutex
+	sessions map[string]memoryEntry
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+func (ms *memoryStore) Load(sid string) ([]byte, time.Time, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	entry, ok := ms.sessions[sid]
+	if !ok {
+		return nil, time.Time{}, ErrSessionNotFound
+	}
+
+	return entry.data, entry.expires, nil
+}
+
+func (ms *memoryStore) Save(sid string, data []byte, expires time.Time) error {
+	ms.lock.Lock()
+	ms.sessions[sid] = memoryEntry{data: data, expires: expires}
+	ms.lock.Unlock()
+
+	return nil
+}
+
+func (ms *memoryStore) Delete(sid string) error {
+	ms.lock.Lock()
+	delete(ms.sessions, sid)
+	ms.lock.Unlock()
+
+	return nil
+}
+
+func (ms *memoryStore) GC() error {
+	now := time.Now()
+
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	for sid, entry := range ms.sessions {
+		if !entry.expires.After(now) {
+			delete(ms.sessions, sid)
+		}
+	}
+
+	return nil
+}
+
+func (ms *memoryStore) Ping(context.Context) error {
+	return nil
+}
+
+func (ms *memoryStore) Count() (int, error) {
+	ms.lock.RLock()
+	defer ms.lock.RUnlock()
+
+	return len(ms.sessions), nil
+}
@@ -1,38 +1,140 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/24        Li Zebang
- */
-
-package utility
-
-func Paging(page, pageSize uint64) (pageStart, pageEnd uint64) {
-	if page == 0 {
-		page = 1
-	}
-
-	return (page - 1) * pageSize, page * pageSize
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/24        Li Zebang
+ */
+
+package utility
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Paging returns the offset and the row count of a page.
+func Paging(page, pageSize uint64) (offset, limit uint64) {
+	if page == 0 {
+		page = 1
+	}
+
+	return (page - 1) * pageSize, pageSize
+}
+
+// Cursor marks the last row of a page of a list ordered by created and id,
+// newest first.
+type Cursor struct {
+	Created time.Time
+	ID      uint64
+}
+
+// String returns the opaque form of c handed to clients.
+func (c Cursor) String() string {
+	raw := fmt.Sprintf("%d.%d", c.Created.UnixNano(), c.ID)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor reads a cursor returned by Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var (
+		nsec int64
+		id   uint64
+	)
+
+	if n, err := fmt.Sscanf(string(raw), "%d.%d", &nsec, &id); err != nil || n != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{Created: time.Unix(0, nsec), ID: id}, nil
+}
+
+// Pager selects one page of a list: the rows after a cursor when one is
+// given, the rows of a page number otherwise.
+type Pager struct {
+	Page     uint64
+	PageSize uint64
+	After    *Cursor
+}
+
+// NewPager returns the pager of a list request. Page is zero in cursor mode.
+func NewPager(page, pageSize uint64, cursor string) (*Pager, error) {
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	if cursor == "" {
+		if page == 0 {
+			page = 1
+		}
+
+		return &Pager{Page: page, PageSize: pageSize}, nil
+	}
+
+	after, err := ParseCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pager{PageSize: pageSize, After: after}, nil
+}
+
+// Clause returns the keyset condition, ordering and limit to append to a
+// query that already has a WHERE clause, with their arguments. One row more
+// than the page size is selected so that More can tell whether a next page
+// exists.
+func (p *Pager) Clause() (string, []interface{}) {
+	if p.After != nil {
+		return " AND (created < ? OR (created = ? AND id < ?)) ORDER BY created DESC, id DESC LIMIT ?",
+			[]interface{}{p.After.Created, p.After.Created, p.After.ID, p.PageSize + 1}
+	}
+
+	offset, limit := Paging(p.Page, p.PageSize)
+
+	return " ORDER BY created DESC, id DESC LIMIT ?, ?", []interface{}{offset, limit + 1}
+}
+
+// More reports whether n rows selected with Clause run past the page.
+func (p *Pager) More(n int) bool {
+	return uint64(n) > p.PageSize
+}
@@ -1,44 +1,399 @@
-/*
- * MIT License
- *
- * Copyright (c) 2017 SmartestEE Inc.
- *
- * Permission is hereby granted, free of charge, to any person obtaining a copy
- * of this software and associated documentation files (the "Software"), to deal
- * in the Software without restriction, including without limitation the rights
- * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
- * copies of the Software, and to permit persons to whom the Software is
- * furnished to do so, subject to the following conditions:
- *
- * The above copyright notice and this permission notice shall be included in all
- * copies or substantial portions of the Software.
- *
- * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
- * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
- * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
- * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
- * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
- * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
- * SOFTWARE.
- */
-
-/*
- * Revision History:
- *     Initial: 2017/07/19        Yusan Kurban
- */
-
-package utility
-
-import (
-	"github.com/astaxie/session"
-	_ "github.com/astaxie/session/providers/memory"
-
-	"ShopApi/general"
-)
-
-var GlobalSessions *session.Manager
-
-func init() {
-	GlobalSessions, _ = session.NewManager("memory", general.SessionUserID, 3600)
-	go GlobalSessions.GC()
-}
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+/*
+ * Revision History:
+ *     Initial: 2017/07/19        Yusan Kurban
+ */
+
+package utility
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/astaxie/session"
+
+	"ShopApi/general"
+	"ShopApi/log"
+)
+
+// ErrSessionNotFound is returned by a SessionStore for unknown sessions.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStore keeps encoded session values between requests.
+type SessionStore interface {
+	// Load returns the data of session sid and when it expires.
+	Load(sid string) (data []byte, expires time.Time, err error)
+	Save(sid string, data []byte, expires time.Time) error
+	Delete(sid string) error
+	// GC removes the sessions that have expired.
+	GC() error
+	// Count returns the number of live sessions.
+	Count() (int, error)
+	// Ping checks that the store can be reached, cheaply enough to be called
+	// on every readiness probe.
+	Ping(ctx context.Context) error
+}
+
+// SessionConfig configures the SessionManager and its cookie.
+type SessionConfig struct {
+	// Store names a registered SessionStore: "memory", "file" or "mysql".
+	Store      string
+	Path       string // directory of the file store
+	CookieName string
+	Lifetime   time.Duration
+	GCInterval time.Duration
+	// Sliding pushes the expiry back on every request instead of counting
+	// from the login.
+	Sliding  bool
+	Secure   bool
+	HTTPOnly bool
+	SameSite http.SameSite
+}
+
+var (
+	GlobalSessions *SessionManager
+
+	storeLock sync.RWMutex
+	stores    = make(map[string]func(SessionConfig) (SessionStore, error))
+)
+
+func init() {
+	RegisterSessionStore("memory", func(SessionConfig) (SessionStore, error) {
+		return newMemoryStore(), nil
+	})
+	RegisterSessionStore("file", newFileStore)
+
+	GlobalSessions, _ = NewSessionManager(SessionConfig{
+		Store:      "memory",
+		CookieName: general.SessionUserID,
+		Lifetime:   time.Hour,
+		GCInterval: time.Hour,
+		Sliding:    true,
+		HTTPOnly:   true,
+		SameSite:   http.SameSiteLaxMode,
+	})
+}
+
+// RegisterSessionStore makes a SessionStore available under name.
+func RegisterSessionStore(name string, open func(SessionConfig) (SessionStore, error)) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+
+	if _, dup := stores[name]; dup {
+		panic("session: RegisterSessionStore called twice for " + name)
+	}
+	stores[name] = open
+}
+
+// IsValidSessionStore reports whether name is a registered SessionStore.
+func IsValidSessionStore(name string) bool {
+	storeLock.RLock()
+	defer storeLock.RUnlock()
+
+	_, ok := stores[name]
+
+	return ok
+}
+
+// InitSessions replaces GlobalSessions with a manager built from conf.
+func InitSessions(conf SessionConfig) error {
+	manager, err := NewSessionManager(conf)
+	if err != nil {
+		return err
+	}
+
+	GlobalSessions = manager
+
+	return nil
+}
+
+// SessionManager hands out sessions identified by a cookie.
+type SessionManager struct {
+	conf  SessionConfig
+	store SessionStore
+
+	gcLock sync.Mutex
+	gcStop chan struct{}
+}
+
+func NewSessionManager(conf SessionConfig) (*SessionManager, error) {
+	storeLock.RLock()
+	open, ok := stores[conf.Store]
+	storeLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("session: unknown store %q", conf.Store)
+	}
+
+	store, err := open(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionManager{conf: conf, store: store}, nil
+}
+
+// Store returns the SessionStore behind the manager.
+func (m *SessionManager) Store() SessionStore {
+	return m.store
+}
+
+// SessionStart returns the session of the request, starting a new one if the
+// request has none or it has expired.
+func (m *SessionManager) SessionStart(w http.ResponseWriter, r *http.Request) session.Session {
+	if cookie, err := r.Cookie(m.conf.CookieName); err == nil && cookie.Value != "" {
+		sid, _ := url.QueryUnescape(cookie.Value)
+
+		sess, err := m.load(sid)
+		if err == nil {
+			if m.conf.Sliding {
+				m.slide(w, sess)
+			}
+
+			return sess
+		}
+
+		if err != ErrSessionNotFound {
+			log.Logger.Error("Load session with error:", err)
+		}
+	}
+
+	sess := &storedSession{
+		sid:     m.newSessionID(),
+		values:  make(map[interface{}]interface{}),
+		expires: time.Now().Add(m.conf.Lifetime),
+		manager: m,
+	}
+	m.setCookie(w, sess)
+
+	return sess
+}
+
+// SessionDestroy ends the session of the request and clears its cookie.
+func (m *SessionManager) SessionDestroy(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(m.conf.CookieName)
+	if err != nil || cookie.Value == "" {
+		return
+	}
+
+	sid, _ := url.QueryUnescape(cookie.Value)
+	if err = m.store.Delete(sid); err != nil {
+		log.Logger.Error("Destroy session with error:", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.conf.CookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		Secure:   m.conf.Secure,
+		HttpOnly: m.conf.HTTPOnly,
+		SameSite: m.conf.SameSite,
+	})
+}
+
+func (m *SessionManager) load(sid string) (*storedSession, error) {
+	data, expires, err := m.store.Load(sid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !expires.After(time.Now()) {
+		return nil, ErrSessionNotFound
+	}
+
+	values := make(map[interface{}]interface{})
+	if err = gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, err
+	}
+
+	return &storedSession{sid: sid, values: values, expires: expires, manager: m}, nil
+}
+
+// slide renews the expiry of sess once a tenth of its lifetime has passed,
+// so that busy sessions aren't written on every request.
+func (m *SessionManager) slide(w http.ResponseWriter, sess *storedSession) {
+	expires := time.Now().Add(m.conf.Lifetime)
+	if expires.Sub(sess.expires) < m.conf.Lifetime/10 {
+		return
+	}
+
+	sess.lock.Lock()
+	sess.expires = expires
+	sess.lock.Unlock()
+
+	if err := sess.save(); err != nil {
+		log.Logger.Error("Renew session with error:", err)
+
+		return
+	}
+
+	m.setCookie(w, sess)
+}
+
+func (m *SessionManager) setCookie(w http.ResponseWriter, sess *storedSession) {
+	maxAge := int(sess.expires.Sub(time.Now()) / time.Second)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.conf.CookieName,
+		Value:    url.QueryEscape(sess.sid),
+		Path:     "/",
+		MaxAge:   maxAge,
+		Expires:  sess.expires,
+		Secure:   m.conf.Secure,
+		HttpOnly: m.conf.HTTPOnly,
+		SameSite: m.conf.SameSite,
+	})
+}
+
+func (m *SessionManager) newSessionID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// StartGC removes expired sessions in the background until StopGC is
+// called.
+func (m *SessionManager) StartGC() {
+	m.gcLock.Lock()
+	defer m.gcLock.Unlock()
+
+	if m.gcStop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	m.gcStop = stop
+
+	go func() {
+		ticker := time.NewTicker(m.conf.GCInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := m.store.GC(); err != nil {
+					log.Logger.Error("Session GC with error:", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopGC stops the goroutine started by StartGC.
+func (m *SessionManager) StopGC() {
+	m.gcLock.Lock()
+	defer m.gcLock.Unlock()
+
+	if m.gcStop != nil {
+		close(m.gcStop)
+		m.gcStop = nil
+	}
+}
+
+// storedSession is a session whose values are written to the store on every
+// change.
+type storedSession struct {
+	lock    sync.RWMutex
+	sid     string
+	values  map[interface{}]interface{}
+	expires time.Time
+	manager *SessionManager
+}
+
+func (ss *storedSession) Set(key, value interface{}) error {
+	ss.lock.Lock()
+	ss.values[key] = value
+	ss.lock.Unlock()
+
+	return ss.save()
+}
+
+func (ss *storedSession) Get(key interface{}) interface{} {
+	ss.lock.RLock()
+	defer ss.lock.RUnlock()
+
+	return ss.values[key]
+}
+
+func (ss *storedSession) Delete(key interface{}) error {
+	ss.lock.Lock()
+	delete(ss.values, key)
+	ss.lock.Unlock()
+
+	return ss.save()
+}
+
+func (ss *storedSession) SessionID() string {
+	return ss.sid
+}
+
+func (ss *storedSession) save() error {
+	var buf bytes.Buffer
+
+	ss.lock.RLock()
+	err := gob.NewEncoder(&buf).Encode(ss.values)
+	expires := ss.expires
+	ss.lock.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return ss.manager.store.Save(ss.sid, buf.Bytes(), expires)
+}
+
+// SessionLifetime is how long an idle login session is kept.
+func SessionLifetime() time.Duration {
+	return GlobalSessions.conf.Lifetime
+}
+
+// DestroySession removes the session sid, logging its owner out.
+func DestroySession(sid string) error {
+	return GlobalSessions.store.Delete(sid)
+}
+
+// StartSessionGC starts the GC of GlobalSessions.
+func StartSessionGC() {
+	GlobalSessions.StartGC()
+}
+
+// StopSessionGC stops the GC of GlobalSessions.
+func StopSessionGC() {
+	GlobalSessions.StopGC()
+}
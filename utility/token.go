@@ -0,0 +1,228 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package utility
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"ShopApi/server/initcache"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+	ErrTokenRevoked = errors.New("token revoked")
+
+	tokenKey   []byte
+	tokenTTL   time.Duration
+	refreshTTL time.Duration
+	adminTTL   time.Duration
+
+	tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+)
+
+// AudienceAdmin is the audience of the access tokens of admins, which the
+// user API refuses.
+const AudienceAdmin = "admin"
+
+// TokenClaims is the payload of an access token. UserID is the ID of an
+// admin when Audience is AudienceAdmin.
+type TokenClaims struct {
+	UserID    uint64 `json:"uid"`
+	SessionID string `json:"sid"`
+	Audience  string `json:"aud,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// InitToken sets the HMAC key and the lifetime of access and refresh tokens
+// and of admin access tokens.
+func InitToken(key string, ttl, refresh, admin time.Duration) {
+	tokenKey = []byte(key)
+	tokenTTL = ttl
+	refreshTTL = refresh
+	adminTTL = admin
+}
+
+// RefreshTokenTTL is how long a refresh token stays valid when unused.
+func RefreshTokenTTL() time.Duration {
+	return refreshTTL
+}
+
+// NewToken issues an HS256 signed JWT for userID, bound to the login session
+// sid.
+func NewToken(userID uint64, sid string) (string, *TokenClaims) {
+	return newToken(userID, sid, "", tokenTTL)
+}
+
+// NewAdminToken issues an access token for adminID, bound to the admin
+// session sid.
+func NewAdminToken(adminID uint64, sid string) (string, *TokenClaims) {
+	return newToken(adminID, sid, AudienceAdmin, adminTTL)
+}
+
+// AdminTokenTTL is how long an admin access token stays valid.
+func AdminTokenTTL() time.Duration {
+	return adminTTL
+}
+
+func newToken(id uint64, sid, audience string, ttl time.Duration) (string, *TokenClaims) {
+	now := time.Now()
+	claims := &TokenClaims{
+		UserID:    id,
+		SessionID: sid,
+		Audience:  audience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+
+	payload, _ := json.Marshal(claims)
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return unsigned + "." + signToken(unsigned), claims
+}
+
+// ParseToken verifies the signature and expiry of a user access token and
+// returns its claims.
+func ParseToken(token string) (*TokenClaims, error) {
+	return parseToken(token, "")
+}
+
+// ParseAdminToken verifies the signature and expiry of an admin access token
+// and returns its claims.
+func ParseAdminToken(token string) (*TokenClaims, error) {
+	return parseToken(token, AudienceAdmin)
+}
+
+func parseToken(token, audience string) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return nil, ErrInvalidToken
+	}
+
+	if !hmac.Equal([]byte(signToken(parts[0]+"."+parts[1])), []byte(parts[2])) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &TokenClaims{}
+	if err = json.Unmarshal(payload, claims); err != nil || claims.UserID == 0 || claims.Audience != audience {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	if initcache.Bm.IsExist(revokedKey(claims.SessionID)) {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// RevokeToken rejects the access tokens of login session sid from now on,
+// before they expire.
+func RevokeToken(sid string) {
+	ttl := tokenTTL
+	if adminTTL > ttl {
+		ttl = adminTTL
+	}
+
+	initcache.Bm.Put(revokedKey(sid), true, ttl)
+}
+
+func revokedKey(sid string) string {
+	return "revoked:" + sid
+}
+
+// NewSessionID returns a random ID for a login session.
+func NewSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// NewRefreshToken returns a random refresh token for login session sid and
+// the hash to store for it.
+func NewRefreshToken(sid string) (token, hash string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	return sid + "." + secret, hashRefreshSecret(secret)
+}
+
+// ParseRefreshToken splits token into its login session ID and the hash of
+// its secret.
+func ParseRefreshToken(token string) (sid, hash string, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", ErrInvalidToken
+	}
+
+	return parts[0], hashRefreshSecret(parts[1]), nil
+}
+
+// NewResetToken returns a random password reset token and the hash to store
+// for it.
+func NewResetToken() (token, hash string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+
+	return token, HashResetToken(token)
+}
+
+// HashResetToken returns the hash stored for a password reset token.
+func HashResetToken(token string) string {
+	return hashRefreshSecret(token)
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
+func signToken(unsigned string) string {
+	mac := hmac.New(sha256.New, tokenKey)
+	mac.Write([]byte(unsigned))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,47 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package wechat
+
+import (
+	"context"
+	"strings"
+)
+
+// FakePrefix starts the codes accepted by Fake.
+const FakePrefix = "fake-"
+
+// Fake is a provider for development and tests that needs no WeChat app.
+// The code "fake-<name>" logs in the user with the OpenID "openid-<name>"
+// and the nickname <name>; any other code is invalid.
+type Fake struct{}
+
+func (Fake) Exchange(ctx context.Context, code string) (*Identity, error) {
+	name := strings.TrimPrefix(code, FakePrefix)
+	if name == code || name == "" {
+		return nil, ErrInvalidCode
+	}
+
+	return &Identity{OpenID: "openid-" + name, Nickname: name}, nil
+}
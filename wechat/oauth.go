@@ -0,0 +1,142 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the WeChat API server.
+const DefaultEndpoint = "https://api.weixin.qq.com"
+
+// Error codes of the WeChat API meaning that the code can't be used.
+var invalidCodeErrors = map[int]bool{
+	40029: true, // invalid code
+	40163: true, // code been used
+	41008: true, // missing code
+}
+
+// OAuth exchanges codes with the WeChat web OAuth API of an app.
+type OAuth struct {
+	AppID    string
+	Secret   string
+	Endpoint string
+	Client   *http.Client
+}
+
+// NewOAuth returns a provider for the app appID.
+func NewOAuth(appID, secret, endpoint string) *OAuth {
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	return &OAuth{
+		AppID:    appID,
+		Secret:   secret,
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type apiError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+type accessToken struct {
+	apiError
+	AccessToken string `json:"access_token"`
+	OpenID      string `json:"openid"`
+	UnionID     string `json:"unionid"`
+	Scope       string `json:"scope"`
+}
+
+type userInfo struct {
+	apiError
+	Nickname   string `json:"nickname"`
+	HeadImgURL string `json:"headimgurl"`
+}
+
+func (o *OAuth) Exchange(ctx context.Context, code string) (*Identity, error) {
+	var token accessToken
+
+	q := url.Values{
+		"appid":      {o.AppID},
+		"secret":     {o.Secret},
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	}
+
+	if err := o.get(ctx, "/sns/oauth2/access_token", q, &token); err != nil {
+		return nil, err
+	}
+
+	if invalidCodeErrors[token.ErrCode] {
+		return nil, ErrInvalidCode
+	}
+	if token.ErrCode != 0 || token.OpenID == "" {
+		return nil, fmt.Errorf("wechat: exchange code: %d %s", token.ErrCode, token.ErrMsg)
+	}
+
+	id := &Identity{OpenID: token.OpenID, UnionID: token.UnionID}
+
+	// The profile is only readable with the snsapi_userinfo scope; without
+	// it the user logs in with an empty nickname.
+	if strings.Contains(token.Scope, "snsapi_userinfo") {
+		var info userInfo
+
+		q = url.Values{"access_token": {token.AccessToken}, "openid": {token.OpenID}}
+		if err := o.get(ctx, "/sns/userinfo", q, &info); err == nil && info.ErrCode == 0 {
+			id.Nickname, id.Avatar = info.Nickname, info.HeadImgURL
+		}
+	}
+
+	return id, nil
+}
+
+func (o *OAuth) get(ctx context.Context, path string, q url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, o.Endpoint+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("wechat: %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("wechat: %s: %s", path, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
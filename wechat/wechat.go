@@ -0,0 +1,74 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package wechat
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	// ErrInvalidCode is returned for an OAuth code that is unknown, expired
+	// or already used.
+	ErrInvalidCode = errors.New("wechat: invalid code")
+
+	// ErrUnavailable is returned when no provider is configured.
+	ErrUnavailable = errors.New("wechat: login is not configured")
+)
+
+// Identity is a WeChat user as seen by the app.
+type Identity struct {
+	OpenID   string
+	UnionID  string
+	Nickname string
+	Avatar   string
+}
+
+// Provider exchanges the code a client got from the WeChat OAuth page for
+// the identity of the user.
+type Provider interface {
+	Exchange(ctx context.Context, code string) (*Identity, error)
+}
+
+var provider atomic.Value
+
+type holder struct{ p Provider }
+
+// Use sets the provider used by Exchange; nil disables WeChat login.
+func Use(p Provider) {
+	provider.Store(holder{p})
+}
+
+// Exchange returns the identity behind code through the configured
+// provider.
+func Exchange(ctx context.Context, code string) (*Identity, error) {
+	h, _ := provider.Load().(holder)
+	if h.p == nil {
+		return nil, ErrUnavailable
+	}
+
+	return h.p.Exchange(ctx, code)
+}
@@ -0,0 +1,101 @@
+/*
+ * MIT License
+ *
+ * Copyright (c) 2017 SmartestEE Inc.
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package wechat
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOAuthExchange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		switch r.URL.Path {
+		case "/sns/oauth2/access_token":
+			if q.Get("appid") != "app" || q.Get("secret") != "secret" {
+				t.Errorf("access_token called with %v", q)
+			}
+
+			switch q.Get("code") {
+			case "good":
+				w.Write([]byte(`{"access_token":"at","openid":"o1","unionid":"u1","scope":"snsapi_userinfo"}`))
+			case "used":
+				w.Write([]byte(`{"errcode":40163,"errmsg":"code been used"}`))
+			default:
+				w.Write([]byte(`{"errcode":40013,"errmsg":"invalid appid"}`))
+			}
+		case "/sns/userinfo":
+			if q.Get("access_token") != "at" || q.Get("openid") != "o1" {
+				t.Errorf("userinfo called with %v", q)
+			}
+			w.Write([]byte(`{"nickname":"Li","headimgurl":"https://example.com/li.png"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	o := NewOAuth("app", "secret", server.URL)
+
+	id, err := o.Exchange(context.Background(), "good")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *id != (Identity{OpenID: "o1", UnionID: "u1", Nickname: "Li", Avatar: "https://example.com/li.png"}) {
+		t.Errorf("Exchange = %+v", id)
+	}
+
+	if _, err = o.Exchange(context.Background(), "used"); err != ErrInvalidCode {
+		t.Errorf("Exchange of a used code = %v, want ErrInvalidCode", err)
+	}
+
+	if _, err = o.Exchange(context.Background(), "other"); err == nil || err == ErrInvalidCode {
+		t.Errorf("Exchange with a bad app = %v, want an API error", err)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	Use(nil)
+	if _, err := Exchange(context.Background(), "fake-li"); err != ErrUnavailable {
+		t.Errorf("Exchange without a provider = %v, want ErrUnavailable", err)
+	}
+
+	Use(Fake{})
+	defer Use(nil)
+
+	id, err := Exchange(context.Background(), "fake-li")
+	if err != nil || id.OpenID != "openid-li" || id.Nickname != "li" {
+		t.Errorf("Exchange(fake-li) = %+v, %v", id, err)
+	}
+
+	for _, code := range []string{"", "fake-", "li"} {
+		if _, err = Exchange(context.Background(), code); err != ErrInvalidCode {
+			t.Errorf("Exchange(%q) = %v, want ErrInvalidCode", code, err)
+		}
+	}
+}
@@ -27,7 +27,8 @@ CREATE TABLE IF NOT EXISTS `categories` (
   `status` int(11) NOT NULL,
   `remark` varchar(1000) DEFAULT NULL,
   `created` datetime NOT NULL DEFAULT current_timestamp,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  KEY `idx_pid_status_created` (`pid`, `status`, `created`, `id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=1000 DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
 -- ----------------------------------------------------------
@@ -43,7 +44,8 @@ CREATE TABLE IF NOT EXISTS `contact` (
   `address` varchar(200) NOT NULL DEFAULT '',
   `created` datetime NOT NULL DEFAULT current_timestamp,
   `isdefault` TINYINT(1) DEFAULT NULL,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  KEY `idx_userid_created` (`userid`, `created`, `id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=1000 DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
 -- ----------------------------------------------------------
@@ -74,7 +76,8 @@ CREATE TABLE IF NOT EXISTS `orders` (
   `status` int(11) NOT NULL,
   `created` datetime NOT NULL DEFAULT current_timestamp,
   `payway` INT  NOT NULL ,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  KEY `idx_userid_created` (`userid`, `created`, `id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=1000 DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
 -- ----------------------------------------------------------
@@ -96,7 +99,8 @@ CREATE TABLE IF NOT EXISTS `products` (
   `detail` longtext NOT NULL,
   `created` datetime NOT NULL DEFAULT current_timestamp,
   `inventory` int(11) unsigned NOT NULL,
-  PRIMARY KEY (`id`)
+  PRIMARY KEY (`id`),
+  KEY `idx_category_status_created` (`category`, `status`, `created`, `id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=1000 DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
 -- ----------------------------------------------------------
@@ -104,13 +108,15 @@ CREATE TABLE IF NOT EXISTS `products` (
 
 CREATE TABLE IF NOT EXISTS `users` (
   `id` int(11) unsigned NOT NULL AUTO_INCREMENT,
-  `openid` text,
+  `openid` varchar(64) DEFAULT NULL COMMENT '绑定的微信 openid，未绑定为 NULL',
   `name` varchar(100) DEFAULT NULL UNIQUE ,
   `password` varchar(128) NOT NULL DEFAULT '',
   `status` int(11) DEFAULT NULL,
   `type` INT(11)  NOT NULL,
   `created` datetime NOT NULL DEFAULT current_timestamp,
-  PRIMARY KEY (`id`)
+  `suspendeduntil` datetime DEFAULT NULL COMMENT '停用到期时间，为空表示停用不会自动解除',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_openid` (`openid`)
 ) ENGINE=InnoDB AUTO_INCREMENT=1000 DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
 -- ----------------------------------------------------------
@@ -123,5 +129,183 @@ CREATE TABLE IF NOT EXISTS `userinfo` (
   `email`    VARCHAR(100)         DEFAULT NULL,
   `phone`    VARCHAR(20) NOT NULL DEFAULT '',
   `sex`      TINYINT(1)           DEFAULT NULL COMMENT '0:男;1:女',
+  `phoneverified` TINYINT(1) NOT NULL DEFAULT '0' COMMENT '手机号已通过短信验证',
   PRIMARY KEY (`userid`)
 )ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `usersession` (
+  `id` varchar(32) NOT NULL,
+  `userid` int(11) unsigned NOT NULL,
+  `kind` varchar(16) NOT NULL COMMENT 'session: cookie 登录; token: access/refresh token 登录',
+  `cookieid` varchar(64) NOT NULL DEFAULT '',
+  `refreshhash` varchar(64) NOT NULL DEFAULT '',
+  `device` varchar(128) NOT NULL DEFAULT '',
+  `ip` varchar(64) NOT NULL DEFAULT '',
+  `status` int(11) NOT NULL,
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  `lastseen` datetime NOT NULL DEFAULT current_timestamp,
+  `expires` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_userid_status` (`userid`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `sessiondata` (
+  `id` varchar(64) NOT NULL,
+  `data` blob NOT NULL,
+  `expires` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_expires` (`expires`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `admin` (
+  `id` int(16) unsigned NOT NULL AUTO_INCREMENT,
+  `username` varchar(64) NOT NULL COMMENT '用户名',
+  `password` varchar(128) NOT NULL COMMENT '密码',
+  `email` varchar(64) NOT NULL DEFAULT '' COMMENT '邮箱',
+  `phone` varchar(20) NOT NULL DEFAULT '' COMMENT '手机号',
+  `name` varchar(64) NOT NULL DEFAULT '' COMMENT '真实姓名',
+  `status` int(8) NOT NULL DEFAULT '0' COMMENT '状态: 0 正常; 1 停用',
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  `updated` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_username` (`username`)
+) ENGINE=InnoDB  AUTO_INCREMENT=1000 DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `adminsession` (
+  `id` varchar(32) NOT NULL,
+  `adminid` int(16) unsigned NOT NULL,
+  `device` varchar(128) NOT NULL DEFAULT '',
+  `ip` varchar(64) NOT NULL DEFAULT '',
+  `status` int(11) NOT NULL,
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  `lastseen` datetime NOT NULL DEFAULT current_timestamp,
+  `expires` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_adminid_status` (`adminid`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `role` (
+  `id` int(16) unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(32) NOT NULL COMMENT '角色名',
+  `description` varchar(128) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  `updated` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_name` (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `rolepermission` (
+  `roleid` int(16) unsigned NOT NULL,
+  `permission` varchar(32) NOT NULL COMMENT '权限，如 product:write；* 表示全部权限',
+  PRIMARY KEY (`roleid`, `permission`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `adminrole` (
+  `adminid` int(16) unsigned NOT NULL,
+  `roleid` int(16) unsigned NOT NULL,
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY (`adminid`, `roleid`),
+  KEY `idx_roleid` (`roleid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+INSERT IGNORE INTO `role` (`id`, `name`, `description`) VALUES
+  (1, 'super', '超级管理员'),
+  (2, 'catalog', '商品管理员'),
+  (3, 'order', '订单操作员'),
+  (4, 'service', '客服');
+
+INSERT IGNORE INTO `rolepermission` (`roleid`, `permission`) VALUES
+  (1, '*'),
+  (2, 'product:write'),
+  (2, 'category:write'),
+  (3, 'order:write'),
+  (4, 'order:write'),
+  (4, 'order:refund'),
+  (4, 'user:manage');
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `adminaudit` (
+  `id` int(16) unsigned NOT NULL AUTO_INCREMENT,
+  `adminid` int(16) unsigned NOT NULL,
+  `action` varchar(128) NOT NULL COMMENT '被拒绝的路由或角色操作',
+  `permission` varchar(32) NOT NULL DEFAULT '',
+  `result` varchar(16) NOT NULL COMMENT 'denied 或 granted',
+  `detail` varchar(255) NOT NULL DEFAULT '',
+  `ip` varchar(64) NOT NULL DEFAULT '',
+  `requestid` varchar(128) NOT NULL DEFAULT '',
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY (`id`),
+  KEY `idx_adminid_created` (`adminid`, `created`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `userstatushistory` (
+  `id` int(16) unsigned NOT NULL AUTO_INCREMENT,
+  `userid` int(11) unsigned NOT NULL,
+  `fromstatus` int(11) NOT NULL,
+  `tostatus` int(11) NOT NULL,
+  `reason` varchar(255) NOT NULL DEFAULT '',
+  `until` datetime DEFAULT NULL COMMENT '停用到期时间',
+  `adminid` int(16) unsigned NOT NULL DEFAULT '0' COMMENT '0 表示停用到期后自动恢复',
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY (`id`),
+  KEY `idx_userid_created` (`userid`, `created`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `smscode` (
+  `id` int(16) unsigned NOT NULL AUTO_INCREMENT,
+  `phone` varchar(16) NOT NULL,
+  `purpose` varchar(16) NOT NULL COMMENT 'register, login, reset, rebind',
+  `code` varchar(8) NOT NULL,
+  `ip` varchar(64) NOT NULL DEFAULT '',
+  `attempts` int(11) NOT NULL DEFAULT '0' COMMENT '错误次数',
+  `used` tinyint(1) NOT NULL DEFAULT '0',
+  `expires` datetime NOT NULL,
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY (`id`),
+  KEY `idx_phone_purpose` (`phone`, `purpose`),
+  KEY `idx_ip_created` (`ip`, `created`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+-- ----------------------------------------------------------
+
+
+CREATE TABLE IF NOT EXISTS `passwordreset` (
+  `id` int(16) unsigned NOT NULL AUTO_INCREMENT,
+  `userid` int(11) unsigned NOT NULL,
+  `tokenhash` char(64) NOT NULL COMMENT '重置凭证的 SHA-256',
+  `used` tinyint(1) NOT NULL DEFAULT '0',
+  `expires` datetime NOT NULL,
+  `created` datetime NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_tokenhash` (`tokenhash`),
+  KEY `idx_userid` (`userid`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- 升级按旧版 shop.sql 建立的数据库，按顺序执行。
+
+USE `shop`;
+
+-- ----------------------------------------------------------
+-- users.openid：未绑定为 NULL，并建立唯一索引
+
+UPDATE `users` SET `openid` = NULL WHERE `openid` = '';
+
+ALTER TABLE `users`
+  MODIFY `openid` varchar(64) DEFAULT NULL COMMENT '绑定的微信 openid，未绑定为 NULL',
+  ADD UNIQUE KEY `uk_openid` (`openid`);
+
+-- ----------------------------------------------------------
+-- userinfo.phoneverified：手机号是否已通过短信验证
+
+ALTER TABLE `userinfo`
+  ADD COLUMN `phoneverified` TINYINT(1) NOT NULL DEFAULT '0' COMMENT '手机号已通过短信验证';